@@ -36,12 +36,16 @@ html2json gen --cate weixin	生成微信小程序支持的HTML标签
 html2json gen --cate baidu		生成百度小程序支持的HTML标签
 html2json gen --cate qq		生成QQ小程序支持的HTML标签
 html2json gen --cate toutiao	生成头条小程序支持的HTML标签
+html2json gen --cate weixin --attrs	生成微信小程序支持的HTML标签以及各标签允许的属性
 `,
 	Run: func(cmd *cobra.Command, args []string) {
 		cate := cmd.Flag("cate").Value.String()
-		tags := html2json.GetTags(html2json.Tag(cate))
+		var v interface{} = html2json.GetTags(html2json.Tag(cate))
+		if cmd.Flag("attrs").Value.String() == "true" {
+			v = html2json.GetProfile(html2json.Tag(cate))
+		}
 		file := fmt.Sprintf("%v.json", cate)
-		b, err := json.Marshal(tags)
+		b, err := json.Marshal(v)
 		if err != nil {
 			panic(err)
 		}
@@ -60,6 +64,7 @@ func init() {
 	// Cobra supports Persistent Flags which will work for this command
 	// and all subcommands, e.g.:
 	genCmd.PersistentFlags().String("cate", "app", "小程序分类")
+	genCmd.PersistentFlags().Bool("attrs", false, "同时生成各标签允许的属性")
 
 	// Cobra supports local flags which will only run when this command
 	// is called directly, e.g.:
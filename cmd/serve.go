@@ -16,12 +16,16 @@ package cmd
 
 import (
 	"compress/gzip"
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
 	"io/ioutil"
 	"net/http"
+	"net/url"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/TruthHun/html2json/html2json"
@@ -38,10 +42,10 @@ var serveCmd = &cobra.Command{
 	Long:  `以HTTP接口的形式提供HTML转JSON的服务`,
 	Run: func(cmd *cobra.Command, args []string) {
 		var (
-			tags []string
-			port int
-			err  error
-			b    []byte
+			profile html2json.Profile
+			port    int
+			err     error
+			b       []byte
 		)
 		if port, err = strconv.Atoi(cmd.Flag("port").Value.String()); err != nil {
 			fmt.Println(err.Error())
@@ -54,14 +58,49 @@ var serveCmd = &cobra.Command{
 			if b, err = ioutil.ReadFile(tagsFile); err != nil {
 				fmt.Println(err.Error())
 				fmt.Println("使用默认HTML标签")
+			} else if profile, err = parseProfile(b); err != nil {
+				fmt.Println(err.Error())
+				fmt.Println("使用默认HTML标签")
+			}
+		}
+		r := html2json.NewDefault()
+		if len(profile.Tags) > 0 || profile.Attrs != nil {
+			r = html2json.NewWithProfile(profile)
+		}
+		headingIDs, _ := cmd.Flags().GetBool("heading-ids")
+		r.SetHeadingIDs(headingIDs)
+
+		fetcher := html2json.NewFetcher()
+		fetcher.InsecureSkipVerify, _ = cmd.Flags().GetBool("insecure")
+		fetcher.Policy.AllowPrivate, _ = cmd.Flags().GetBool("allow-private")
+		fetcher.Policy.AllowHosts, _ = cmd.Flags().GetStringArray("allow-host")
+		fetcher.Policy.DenyHosts, _ = cmd.Flags().GetStringArray("deny-host")
+		fetcher.Policy.AllowCIDRs, _ = cmd.Flags().GetStringArray("allow-cidr")
+		fetcher.Policy.DenyCIDRs, _ = cmd.Flags().GetStringArray("deny-cidr")
+		if proxy := cmd.Flag("proxy").Value.String(); proxy != "" {
+			if u, err := url.Parse(proxy); err != nil {
+				fmt.Println(err.Error())
+				fmt.Println("使用环境变量中的代理")
 			} else {
-				if err = json.Unmarshal(b, &tags); err != nil {
-					fmt.Println(err.Error())
-					fmt.Println("使用默认HTML标签")
-				}
+				fetcher.Proxy = http.ProxyURL(u)
 			}
 		}
-		serve(port, tags...)
+		r.SetFetcher(fetcher)
+		assetBase, _ := cmd.Flags().GetString("asset-base")
+		linkBase, _ = cmd.Flags().GetString("link-base")
+		r.SetLinkOptions(html2json.LinkOptions{AssetBase: assetBase, LinkBase: linkBase})
+
+		// 缓存 url 链接的页面以及转换结果
+		cacheTTL, _ := cmd.Flags().GetDuration("cache-ttl")
+		if cacheDir := cmd.Flag("cache-dir").Value.String(); cacheDir != "" {
+			r.SetCache(html2json.NewDiskCache(cacheDir), cacheTTL)
+		} else if cacheSize, _ := cmd.Flags().GetInt("cache-size"); cacheSize > 0 {
+			r.SetCache(html2json.NewMemoryCache(cacheSize), cacheTTL)
+		}
+
+		selectors, _ := cmd.Flags().GetStringArray("selector")
+		excludes, _ := cmd.Flags().GetStringArray("exclude")
+		serve(port, r, selectors, excludes)
 	},
 }
 
@@ -73,27 +112,93 @@ func init() {
 	// Cobra supports Persistent Flags which will work for this command
 	// and all subcommands, e.g.:
 	serveCmd.PersistentFlags().Int("port", 8888, "服务监听端口")
-	serveCmd.PersistentFlags().String("tags", "", "自定义的可信任的HTML标签所在的json文件路径")
+	serveCmd.PersistentFlags().String("tags", "", "自定义的可信任的HTML标签所在的json文件路径，可以是标签数组或者包含 tags 和 attrs 的对象")
+	serveCmd.PersistentFlags().StringArray("selector", nil, "默认只转换匹配该CSS选择器的元素，可指定多次")
+	serveCmd.PersistentFlags().StringArray("exclude", nil, "默认不转换匹配该CSS选择器的元素，可指定多次")
+	serveCmd.PersistentFlags().Bool("heading-ids", false, "为 h1~h6 生成 id，并替换文档内指向标题的链接")
+	serveCmd.PersistentFlags().String("proxy", "", "获取url链接内容时使用的代理，如 http://127.0.0.1:1080，默认使用环境变量中的代理")
+	serveCmd.PersistentFlags().Bool("insecure", false, "获取url链接内容时不校验HTTPS证书")
+	serveCmd.PersistentFlags().Bool("allow-private", false, "允许url链接访问私有、回环、链路本地等内网地址")
+	serveCmd.PersistentFlags().StringArray("allow-host", nil, "只允许url链接访问该域名及其子域名，可指定多次")
+	serveCmd.PersistentFlags().StringArray("deny-host", nil, "禁止url链接访问该域名及其子域名，可指定多次")
+	serveCmd.PersistentFlags().StringArray("allow-cidr", nil, "允许url链接访问的网段或IP，可用于放行内网中的服务，可指定多次")
+	serveCmd.PersistentFlags().StringArray("deny-cidr", nil, "禁止url链接访问的网段或IP，可指定多次")
+	serveCmd.PersistentFlags().String("asset-base", "", "补全图片、音视频等静态资源的相对链接使用的域名，如CDN的域名，默认使用请求的domain参数")
+	serveCmd.PersistentFlags().String("link-base", "", "补全a标签的相对链接使用的域名，默认使用请求的domain参数")
+	serveCmd.PersistentFlags().Int("cache-size", 0, "在内存中缓存的url链接数量，为 0 时不缓存")
+	serveCmd.PersistentFlags().String("cache-dir", "", "缓存url链接内容的目录，指定后不再使用内存缓存")
+	serveCmd.PersistentFlags().Duration("cache-ttl", 5*time.Minute, "缓存的有效期，超过有效期之后使用 ETag、Last-Modified 验证页面是否变化")
 
 	// Cobra supports local flags which will only run when this command
 	// is called directly, e.g.:
 	// serveCmd.Flags().BoolP("toggle", "t", false, "Help message for toggle")
 }
 
+// parseProfile 解析 --tags 文件，兼容只有标签的数组格式
+func parseProfile(b []byte) (profile html2json.Profile, err error) {
+	var tags []string
+	if err = json.Unmarshal(b, &tags); err == nil {
+		profile.Tags = tags
+		return
+	}
+	if err = json.Unmarshal(b, &profile); err != nil {
+		profile = html2json.Profile{}
+	}
+	return
+}
+
 type Response struct {
-	Error string      `json:"error,omitempty"`
+	Error string `json:"error,omitempty"`
+	// url链接违反网络访问策略时为 html2json.PolicyError 的 Code
+	Code  string      `json:"code,omitempty"`
 	IsOK  bool        `json:"is_ok"`
 	Nodes interface{} `json:"nodes,omitempty"`
+	// 节点中的图片、链接以及文本统计，流式输出时不返回
+	Title  string               `json:"title,omitempty"`
+	Images []html2json.Image    `json:"images,omitempty"`
+	Links  []html2json.Link     `json:"links,omitempty"`
+	TOC    []html2json.TOCItem  `json:"toc,omitempty"`
+	Stats  *html2json.TextStats `json:"stats,omitempty"`
+	// 被安全过滤移除的属性
+	Removed []html2json.Removed `json:"removed,omitempty"`
+	// 提取正文(extract=article)时返回
+	Byline    string `json:"byline,omitempty"`
+	Published string `json:"published,omitempty"`
+	// 指定 chunk_size 时返回分块的总数，nodes 为 chunk 参数指定的块
+	Chunks int `json:"chunks,omitempty"`
+}
+
+func (resp *Response) setResult(res *html2json.ParseResult) {
+	resp.Nodes, resp.Title, resp.Images, resp.Links, resp.TOC, resp.Stats = res.Nodes, res.Title, res.Images, res.Links, res.TOC, &res.Stats
+	resp.Removed = res.Removed
 }
 
-var rt = html2json.NewDefault()
+// setError 设置错误信息，url链接违反网络访问策略时同时设置 Code
+func (resp *Response) setError(err error) {
+	resp.IsOK = err == nil
+	if err == nil {
+		return
+	}
+	resp.Error = err.Error()
+	var policyErr *html2json.PolicyError
+	if errors.As(err, &policyErr) {
+		resp.Code = policyErr.Code
+	}
+}
 
-func serve(port int, tag ...string) {
+var (
+	rt = html2json.NewDefault()
+	// 启动服务时指定的默认需要转换和排除的元素，只对 /html2json 生效
+	defaultSelectors, defaultExcludes []string
+	// 启动服务时指定的 a 标签链接的域名，用于区分站内链接
+	linkBase string
+)
+
+func serve(port int, r *html2json.RichText, selectors, excludes []string) {
 	app := gin.New()
 
-	if len(tag) > 0 {
-		rt = html2json.New(tag)
-	}
+	rt = r
+	defaultSelectors, defaultExcludes = selectors, excludes
 
 	// 设置跨域和gzip
 	app.Use(ginzip.Gzip(gzip.BestCompression), cors.New(cors.Config{
@@ -105,9 +210,11 @@ func serve(port int, tag ...string) {
 	}), gin.Recovery())
 
 	app.GET("/", func(ctx *gin.Context) { ctx.JSON(http.StatusOK, gin.H{"pong": "hello html2json!"}) })
-	app.GET("/html2json", html2JSON)  // params: url, timeout
-	app.POST("/html2json", html2JSON) // params: html
-	app.POST("/md2json", md2json)     // params: markdown
+	app.GET("/html2json", html2JSON)       // params: url, timeout, selector, exclude, extract, chunk_size, chunk
+	app.POST("/html2json", html2JSON)      // params: html, selector, exclude, chunk_size, chunk, stream
+	app.POST("/md2json", md2json)          // params: markdown, chunk_size, chunk, stream
+	app.GET("/html2json/v2", html2JSONV2)  // params: url, timeout, selector, exclude
+	app.POST("/html2json/v2", html2JSONV2) // params: html, selector, exclude
 
 	fmt.Println("serve on port:", port)
 	err := app.Run(fmt.Sprintf(":%v", port))
@@ -116,9 +223,134 @@ func serve(port int, tag ...string) {
 	}
 }
 
+// richText 根据请求中的 selector 和 exclude 参数返回本次解析使用的 RichText。
+// selector 会替换启动服务时指定的默认值，exclude 则在默认值的基础上追加
+func richText(ctx *gin.Context) (r *html2json.RichText, scope bool) {
+	var selectors, excludes []string
+	if ctx.Request.Method == http.MethodPost {
+		selectors, excludes = ctx.PostFormArray("selector"), ctx.PostFormArray("exclude")
+	} else {
+		selectors, excludes = ctx.QueryArray("selector"), ctx.QueryArray("exclude")
+	}
+	if len(selectors) == 0 {
+		selectors = defaultSelectors
+	}
+	excludes = append(append([]string{}, defaultExcludes...), excludes...)
+	if len(selectors) == 0 && len(excludes) == 0 {
+		return rt, false
+	}
+	return rt.Clone().SetSelectors(selectors...).SetExcludes(excludes...), true
+}
+
+// param 获取 GET 请求的 query 参数或者 POST 请求的表单参数
+func param(ctx *gin.Context, key string) string {
+	if ctx.Request.Method == http.MethodPost {
+		return ctx.PostForm(key)
+	}
+	return ctx.Query(key)
+}
+
+// chunkNodes 指定了 chunk_size 参数时将节点分块，只返回 chunk 参数指定的块，chunk 从 0 开始
+func chunkNodes(ctx *gin.Context, resp *Response) error {
+	size, _ := strconv.Atoi(param(ctx, "chunk_size"))
+	if size <= 0 {
+		return nil
+	}
+	index, _ := strconv.Atoi(param(ctx, "chunk"))
+	nodes, _ := resp.Nodes.([]html2json.Node)
+	chunks := html2json.Chunk(nodes, size)
+	resp.Chunks = len(chunks)
+	if len(chunks) == 0 && index == 0 {
+		resp.Nodes = nil
+		return nil
+	}
+	if index < 0 || index >= len(chunks) {
+		resp.Nodes = nil
+		return errors.New("chunk is out of range")
+	}
+	resp.Nodes = chunks[index]
+	return nil
+}
+
 func html2JSON(ctx *gin.Context) {
 	var err error
 	resp := Response{IsOK: true}
+	rt, scope := richText(ctx)
+	switch ctx.Request.Method {
+	case http.MethodPost:
+		htmlStr := ctx.DefaultPostForm("html", "")
+		domain := ctx.DefaultPostForm("domain", "")
+		if htmlStr == "" {
+			err = errors.New("html is empty")
+		} else if param(ctx, "stream") == "1" {
+			// 流式输出不支持指定需要转换或排除的元素以及分块
+			if scope || param(ctx, "chunk_size") != "" {
+				err = errors.New("stream does not support selector, exclude or chunk_size")
+				break
+			}
+			if err = rt.CheckEncode(); err != nil {
+				break
+			}
+			streamJSON(ctx, strings.NewReader(htmlStr), html2json.EncodeOptions{Domain: domain})
+			return
+		} else {
+			var res *html2json.ParseResult
+			if res, err = rt.ParseResultContext(ctx.Request.Context(), htmlStr, domain); err == nil {
+				resp.setResult(res)
+			}
+		}
+	case http.MethodGet:
+		urlStr := ctx.DefaultQuery("url", "")
+		domain := ctx.DefaultQuery("domain", "")
+		timeout, _ := strconv.Atoi(ctx.DefaultQuery("timeout", "10"))
+		if urlStr == "" {
+			err = errors.New("url is empty")
+		} else {
+			if domain == "" {
+				domain = urlStr
+			}
+			c, cancel := withTimeout(ctx, timeout)
+			defer cancel()
+			switch ctx.DefaultQuery("extract", "") {
+			case "":
+				var res *html2json.ParseResult
+				if res, err = rt.ParseResultByURLContext(c, urlStr, domain); err == nil {
+					resp.setResult(res)
+				}
+			case "article":
+				var a *html2json.Article
+				if a, err = rt.ParseArticleByURLContext(c, urlStr, domain); err == nil {
+					site := linkBase
+					if site == "" {
+						site = domain
+					}
+					resp.setResult(html2json.NewParseResult(a.Nodes, site))
+					if a.Title != "" {
+						resp.Title = a.Title
+					}
+					resp.Byline, resp.Published = a.Byline, a.Published
+				}
+			default:
+				err = errors.New("extract is not supported")
+			}
+		}
+	default:
+		err = errors.New("request method is not allow")
+	}
+	if err == nil {
+		err = chunkNodes(ctx, &resp)
+	}
+	resp.setError(err)
+	ctx.JSON(http.StatusOK, resp)
+}
+
+// html2JSONV2 将 HTML 转换为片段，音视频、iframe 等媒体以及代码块、表格、公式单独作为一个片段
+func html2JSONV2(ctx *gin.Context) {
+	var (
+		err      error
+		segments []html2json.Segment
+	)
+	rt, _ := richText(ctx)
 	switch ctx.Request.Method {
 	case http.MethodPost:
 		htmlStr := ctx.DefaultPostForm("html", "")
@@ -126,7 +358,7 @@ func html2JSON(ctx *gin.Context) {
 		if htmlStr == "" {
 			err = errors.New("html is empty")
 		} else {
-			resp.Nodes, err = rt.Parse(htmlStr, domain)
+			segments, err = rt.ParseByByteV2Context(ctx.Request.Context(), []byte(htmlStr), domain)
 		}
 	case http.MethodGet:
 		urlStr := ctx.DefaultQuery("url", "")
@@ -138,18 +370,42 @@ func html2JSON(ctx *gin.Context) {
 			if domain == "" {
 				domain = urlStr
 			}
-			resp.Nodes, err = rt.ParseByURL(urlStr, domain, timeout)
+			c, cancel := withTimeout(ctx, timeout)
+			defer cancel()
+			segments, err = rt.ParseByURLV2Context(c, urlStr, domain)
 		}
 	default:
 		err = errors.New("request method is not allow")
 	}
-	resp.IsOK = err == nil
-	if err != nil {
-		resp.Error = err.Error()
+	var resp Response
+	if resp.setError(err); err == nil {
+		resp.Nodes = segments
 	}
 	ctx.JSON(http.StatusOK, resp)
 }
 
+// streamJSON 边解析边输出 {"nodes":[...],"is_ok":true} 格式的响应，用于指定了 stream=1 的请求，避免在内存中构造完整的节点树
+func streamJSON(ctx *gin.Context, rd io.Reader, opts html2json.EncodeOptions) {
+	ctx.Header("Content-Type", "application/json; charset=utf-8")
+	ctx.Status(http.StatusOK)
+	w := ctx.Writer
+	w.WriteString(`{"nodes":`)
+	err := rt.EncodeContext(ctx.Request.Context(), w, rd, opts)
+	var resp Response
+	resp.setError(err)
+	b, _ := json.Marshal(resp)
+	w.WriteString(",")
+	w.Write(b[1:])
+}
+
+// withTimeout 返回请求的 context，timeout 为超时时间(秒)，默认为 10 秒
+func withTimeout(ctx *gin.Context, timeout int) (context.Context, context.CancelFunc) {
+	if timeout <= 0 {
+		timeout = 10
+	}
+	return context.WithTimeout(ctx.Request.Context(), time.Duration(timeout)*time.Second)
+}
+
 func md2json(ctx *gin.Context) {
 	var err error
 	resp := Response{IsOK: true}
@@ -157,12 +413,20 @@ func md2json(ctx *gin.Context) {
 	domain := ctx.DefaultPostForm("domain", "")
 	if md == "" {
 		err = errors.New("markdown is empty")
+	} else if param(ctx, "stream") == "1" {
+		if param(ctx, "chunk_size") != "" {
+			err = errors.New("stream does not support chunk_size")
+		} else if err = rt.CheckEncode(); err == nil {
+			streamJSON(ctx, strings.NewReader(md), html2json.EncodeOptions{Domain: domain, Markdown: true})
+			return
+		}
 	} else {
-		resp.Nodes, err = rt.ParseMarkdown(md, domain)
-	}
-	resp.IsOK = err == nil
-	if err != nil {
-		resp.Error = err.Error()
+		var res *html2json.ParseResult
+		if res, err = rt.ParseMarkdownResultContext(ctx.Request.Context(), md, domain); err == nil {
+			resp.setResult(res)
+			err = chunkNodes(ctx, &resp)
+		}
 	}
+	resp.setError(err)
 	ctx.JSON(http.StatusOK, resp)
 }
@@ -6,7 +6,8 @@ replace github.com/ugorji/go/codec v0.0.0-20181204163529-d75b2dcb6bc8 => github.
 
 require (
 	github.com/PuerkitoBio/goquery v1.5.0
-	github.com/astaxie/beego v1.12.0
+	github.com/alecthomas/chroma v0.10.0
+	github.com/andybalholm/cascadia v1.0.0
 	github.com/gin-contrib/cors v1.3.0
 	github.com/gin-contrib/gzip v0.0.1
 	github.com/gin-gonic/gin v1.4.0
@@ -14,4 +15,5 @@ require (
 	github.com/shurcooL/sanitized_anchor_name v1.0.0 // indirect
 	github.com/spf13/cobra v0.0.5
 	golang.org/x/net v0.0.0-20191002035440-2ec189313ef0
+	golang.org/x/text v0.3.2
 )
@@ -0,0 +1,491 @@
+package html2json
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"math"
+	"regexp"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// Article 提取正文的结果
+type Article struct {
+	Title     string `json:"title,omitempty"`
+	Byline    string `json:"byline,omitempty"`    // 作者
+	Published string `json:"published,omitempty"` // 发布时间，为页面中的原始值
+	Nodes     []Node `json:"nodes"`
+}
+
+var (
+	// 可能不是正文的元素的 class 和 id
+	unlikelyRegexp = regexp.MustCompile(`(?i)banner|breadcrumb|combx|comment|community|consent|cookie|disqus|extra|footer|gdpr|header|legends|menu|modal|nav|pager|pagination|popup|related|remark|replies|rss|share|shoutbox|sidebar|skyscraper|social|sponsor|subscribe|ad-break|agegate|toolbar|widget`)
+	maybeRegexp    = regexp.MustCompile(`(?i)and|article|body|column|content|main|shadow`)
+	positiveRegexp = regexp.MustCompile(`(?i)article|body|content|entry|hentry|h-entry|main|page|post|text|blog|story`)
+	negativeRegexp = regexp.MustCompile(`(?i)-ad-|hidden|banner|combx|comment|com-|contact|foot|footnote|gdpr|masthead|media|meta|outbrain|promo|related|scroll|share|shoutbox|sidebar|skyscraper|sponsor|shopping|tags|tool|widget|cookie`)
+	bylineRegexp   = regexp.MustCompile(`(?i)byline|author|dateline|writtenby|p-author`)
+	hiddenRegexp   = regexp.MustCompile(`(?i)display\s*:\s*none|visibility\s*:\s*hidden`)
+)
+
+// 提取正文时直接移除的元素
+var unlikelyTags = map[string]bool{
+	"script": true, "style": true, "noscript": true, "nav": true, "aside": true, "footer": true, "form": true,
+	"button": true, "input": true, "select": true, "textarea": true,
+}
+
+var unlikelyRoles = map[string]bool{
+	"menu": true, "menubar": true, "complementary": true, "navigation": true, "alert": true, "alertdialog": true,
+	"dialog": true, "banner": true, "contentinfo": true,
+}
+
+// 计算得分的段落元素
+var scoreTags = map[string]bool{"p": true, "pre": true, "td": true, "blockquote": true}
+
+// 标题中网站名称等的分隔符
+var titleSeparators = []string{" | ", " - ", " _ ", " – ", " — ", " :: ", " / "}
+
+func (r *RichText) ParseArticle(htmlStr, domain string) (*Article, error) {
+	return r.ParseArticleContext(context.Background(), htmlStr, domain)
+}
+
+// ParseArticleContext 根据文本密度、链接密度以及语义标签找到页面的正文并解析，
+// 同时提取标题、作者以及发布时间
+func (r *RichText) ParseArticleContext(ctx context.Context, htmlStr, domain string) (*Article, error) {
+	if err := r.limits.checkInput(int64(len(htmlStr))); err != nil {
+		return nil, err
+	}
+	return r.parseArticle(ctx, strings.NewReader(htmlStr), domain)
+}
+
+func (r *RichText) ParseArticleByByte(htmlByte []byte, domain string) (*Article, error) {
+	return r.ParseArticleByByteContext(context.Background(), htmlByte, domain)
+}
+
+func (r *RichText) ParseArticleByByteContext(ctx context.Context, htmlByte []byte, domain string) (*Article, error) {
+	if err := r.limits.checkInput(int64(len(htmlByte))); err != nil {
+		return nil, err
+	}
+	htmlByte, err := r.decode(htmlByte, "")
+	if err != nil {
+		return nil, err
+	}
+	return r.parseArticle(ctx, bytes.NewReader(htmlByte), domain)
+}
+
+// ParseArticleByURL 获取链接的 HTML 内容并提取正文，timeout 为超时时间(秒)，默认为 10 秒
+func (r *RichText) ParseArticleByURL(urlStr, domain string, timeout ...int) (*Article, error) {
+	to := 10 * time.Second
+	if len(timeout) > 0 && timeout[0] > 0 {
+		to = time.Duration(timeout[0]) * time.Second
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), to)
+	defer cancel()
+	return r.ParseArticleByURLContext(ctx, urlStr, domain)
+}
+
+func (r *RichText) ParseArticleByURLContext(ctx context.Context, urlStr, domain string) (article *Article, err error) {
+	err = r.fetchParse(ctx, urlStr, domain, "article", &article, func(b []byte) (interface{}, error) {
+		article, err = r.ParseArticleByByteContext(ctx, b, domain)
+		return article, err
+	})
+	return
+}
+
+func (r *RichText) parseArticle(ctx context.Context, reader io.Reader, domain string) (*Article, error) {
+	root, err := html.Parse(reader)
+	if err != nil {
+		return nil, err
+	}
+	if err = r.prepare(root); err != nil {
+		return nil, err
+	}
+
+	// 元数据需要在移除页头等元素之前提取
+	metas := metaContents(root)
+	a := &Article{
+		Title:     articleTitle(root, metas),
+		Byline:    articleByline(root, metas),
+		Published: articlePublished(root, metas),
+	}
+
+	body := findElement(root, atom.Body)
+	if body == nil {
+		return a, nil
+	}
+	if content := extractContent(body); len(content) > 0 {
+		for _, node := range content {
+			node.Parent.RemoveChild(node)
+		}
+		for body.FirstChild != nil {
+			body.RemoveChild(body.FirstChild)
+		}
+		for _, node := range content {
+			body.AppendChild(node)
+		}
+	}
+
+	c := r.newConverter(ctx, domain)
+	c.useBase(root)
+	a.Nodes = c.convert(body)
+	if c.err != nil {
+		return nil, c.err
+	}
+	return a, nil
+}
+
+// extractContent 移除页头、导航、侧边栏等元素之后，返回得分最高的元素以及与之相关的兄弟元素。
+// 找不到正文或者正文为 body 本身时返回 nil
+func extractContent(body *html.Node) []*html.Node {
+	removeUnlikely(body)
+
+	scores := make(map[*html.Node]float64)
+	var candidates []*html.Node
+	addScore := func(node *html.Node, score float64) {
+		if node == nil || node.Type != html.ElementNode {
+			return
+		}
+		if _, ok := scores[node]; !ok {
+			scores[node] = initScore(node)
+			candidates = append(candidates, node)
+		}
+		scores[node] += score
+	}
+
+	eachElement(body, func(node *html.Node) bool {
+		if !scoreTags[node.Data] && !((node.Data == "div" || node.Data == "section") && !hasBlockChild(node)) {
+			return true
+		}
+		text := strings.TrimSpace(nodeText(node))
+		length := utf8.RuneCountInString(text)
+		if length < 25 {
+			return true
+		}
+		// 逗号越多、内容越长，越可能是正文
+		score := 1 + float64(strings.Count(text, ",")+strings.Count(text, "，")+strings.Count(text, "、"))
+		score += math.Min(float64(length)/100, 3)
+		// 祖先元素按层级衰减获得段落的得分
+		for level, p := 0, node.Parent; level < 3 && p != nil && p != body.Parent; level, p = level+1, p.Parent {
+			switch level {
+			case 0:
+				addScore(p, score)
+			case 1:
+				addScore(p, score/2)
+			default:
+				addScore(p, score/float64(level*3))
+			}
+		}
+		return false
+	})
+
+	var (
+		top      *html.Node
+		topScore float64
+	)
+	for _, node := range candidates {
+		scores[node] *= 1 - linkDensity(node)
+		if top == nil || scores[node] > topScore {
+			top, topScore = node, scores[node]
+		}
+	}
+	if top == nil || top == body {
+		return nil
+	}
+
+	// 同一父元素下得分较高或者像是段落的兄弟元素也属于正文
+	threshold := math.Max(10, topScore*0.2)
+	var content []*html.Node
+	for node := top.Parent.FirstChild; node != nil; node = node.NextSibling {
+		if node == top {
+			content = append(content, node)
+			continue
+		}
+		if node.Type != html.ElementNode {
+			continue
+		}
+		if score, ok := scores[node]; ok && score >= threshold {
+			content = append(content, node)
+			continue
+		}
+		if node.Data == "p" {
+			text := strings.TrimSpace(nodeText(node))
+			length, density := utf8.RuneCountInString(text), linkDensity(node)
+			if length > 80 && density < 0.25 || length > 0 && density == 0 && (strings.HasSuffix(text, ".") || strings.HasSuffix(text, "。")) {
+				content = append(content, node)
+			}
+		}
+	}
+	return content
+}
+
+// removeUnlikely 移除不太可能是正文的元素
+func removeUnlikely(parent *html.Node) {
+	for node := parent.FirstChild; node != nil; {
+		next := node.NextSibling
+		if node.Type == html.ElementNode {
+			if unlikely(node) {
+				parent.RemoveChild(node)
+			} else {
+				removeUnlikely(node)
+			}
+		}
+		node = next
+	}
+}
+
+func unlikely(node *html.Node) bool {
+	if unlikelyTags[node.Data] || unlikelyRoles[getAttr(node, "role")] {
+		return true
+	}
+	if _, ok := findAttr(node, "hidden"); ok || hiddenRegexp.MatchString(getAttr(node, "style")) {
+		return true
+	}
+	switch node.Data {
+	case "body", "a", "article", "main":
+		return false
+	}
+	match := getAttr(node, "class") + " " + getAttr(node, "id")
+	return unlikelyRegexp.MatchString(match) && !maybeRegexp.MatchString(match)
+}
+
+// initScore 根据标签以及 class 和 id 计算元素的初始得分
+func initScore(node *html.Node) (score float64) {
+	switch node.Data {
+	case "article", "main":
+		score = 10
+	case "div":
+		score = 5
+	case "pre", "td", "blockquote":
+		score = 3
+	case "address", "ol", "ul", "dl", "dd", "dt", "li", "form":
+		score = -3
+	case "h1", "h2", "h3", "h4", "h5", "h6", "th":
+		score = -5
+	}
+	if getAttr(node, "itemprop") == "articleBody" {
+		score += 25
+	}
+	for _, key := range []string{"class", "id"} {
+		val := getAttr(node, key)
+		if val == "" {
+			continue
+		}
+		if negativeRegexp.MatchString(val) {
+			score -= 25
+		}
+		if positiveRegexp.MatchString(val) {
+			score += 25
+		}
+	}
+	return
+}
+
+// linkDensity 链接文本占全部文本的比例
+func linkDensity(node *html.Node) float64 {
+	length := utf8.RuneCountInString(strings.TrimSpace(nodeText(node)))
+	if length == 0 {
+		return 0
+	}
+	var links int
+	eachElement(node, func(n *html.Node) bool {
+		if n.DataAtom == atom.A {
+			links += utf8.RuneCountInString(strings.TrimSpace(nodeText(n)))
+			return false
+		}
+		return true
+	})
+	return math.Min(float64(links)/float64(length), 1)
+}
+
+// 包含这些子元素的 div 和 section 不作为段落计算得分
+var blockElements = map[atom.Atom]bool{
+	atom.Article: true, atom.Blockquote: true, atom.Div: true, atom.Dl: true, atom.Figure: true, atom.Ol: true,
+	atom.P: true, atom.Pre: true, atom.Section: true, atom.Table: true, atom.Ul: true,
+}
+
+func hasBlockChild(node *html.Node) bool {
+	for child := node.FirstChild; child != nil; child = child.NextSibling {
+		if child.Type == html.ElementNode && blockElements[child.DataAtom] {
+			return true
+		}
+	}
+	return false
+}
+
+// articleTitle 优先使用 og:title，其次为去掉网站名称的 title，最后为第一个 h1
+func articleTitle(root *html.Node, metas map[string]string) string {
+	if title := firstMeta(metas, "og:title", "twitter:title"); title != "" {
+		return title
+	}
+	var title, h1 string
+	if node := findElement(root, atom.Title); node != nil {
+		title = collapseSpace(nodeText(node))
+	}
+	if node := findElement(root, atom.H1); node != nil {
+		h1 = collapseSpace(nodeText(node))
+	}
+	switch {
+	case title == "":
+		return h1
+	case h1 != "" && strings.Contains(title, h1):
+		return h1
+	}
+	for _, sep := range titleSeparators {
+		if i := strings.LastIndex(title, sep); i > 0 {
+			return strings.TrimSpace(title[:i])
+		}
+	}
+	return title
+}
+
+// articleByline 从 meta 或者 class、id、rel、itemprop 与作者相关的元素中提取作者
+func articleByline(root *html.Node, metas map[string]string) (byline string) {
+	if byline = firstMeta(metas, "author", "article:author", "dc.creator", "byl"); byline != "" && urlScheme(byline) == "" {
+		return
+	}
+	byline = ""
+	eachElement(root, func(node *html.Node) bool {
+		if node.DataAtom == atom.Meta || node.DataAtom == atom.Link {
+			return false
+		}
+		rel, itemprop := getAttr(node, "rel"), getAttr(node, "itemprop")
+		match := getAttr(node, "class") + " " + getAttr(node, "id")
+		if rel != "author" && !strings.Contains(itemprop, "author") && !bylineRegexp.MatchString(match) {
+			return true
+		}
+		text := collapseSpace(nodeText(node))
+		if content := getAttr(node, "content"); text == "" && content != "" {
+			text = content
+		}
+		if length := utf8.RuneCountInString(text); length > 0 && length < 100 {
+			byline = text
+		}
+		return byline == ""
+	})
+	return
+}
+
+// articlePublished 从 meta、itemprop 以及 time 元素中提取发布时间
+func articlePublished(root *html.Node, metas map[string]string) (published string) {
+	published = firstMeta(metas, "article:published_time", "og:published_time", "datepublished", "pubdate",
+		"publishdate", "publish_date", "dc.date.issued", "dc.date", "date")
+	if published != "" {
+		return
+	}
+	var firstTime string
+	eachElement(root, func(node *html.Node) bool {
+		if node.DataAtom == atom.Meta {
+			return false
+		}
+		var val string
+		for _, key := range []string{"datetime", "content"} {
+			if val = getAttr(node, key); val != "" {
+				break
+			}
+		}
+		if val == "" {
+			val = collapseSpace(nodeText(node))
+		}
+		switch {
+		case strings.EqualFold(getAttr(node, "itemprop"), "datePublished"):
+			published = val
+		case node.DataAtom == atom.Time && firstTime == "":
+			if _, ok := findAttr(node, "pubdate"); ok {
+				published = val
+			} else {
+				firstTime = val
+			}
+		}
+		return published == ""
+	})
+	if published == "" {
+		published = firstTime
+	}
+	return
+}
+
+// metaContents 获取 meta 元素的内容，key 为小写的 property、name 或者 itemprop
+func metaContents(root *html.Node) map[string]string {
+	metas := make(map[string]string)
+	eachElement(root, func(node *html.Node) bool {
+		if node.DataAtom != atom.Meta {
+			return true
+		}
+		content := strings.TrimSpace(getAttr(node, "content"))
+		if content == "" {
+			return false
+		}
+		for _, key := range []string{"property", "name", "itemprop"} {
+			for _, k := range strings.Fields(strings.ToLower(getAttr(node, key))) {
+				if _, ok := metas[k]; !ok {
+					metas[k] = content
+				}
+			}
+		}
+		return false
+	})
+	return metas
+}
+
+func firstMeta(metas map[string]string, keys ...string) string {
+	for _, key := range keys {
+		if val := metas[key]; val != "" {
+			return val
+		}
+	}
+	return ""
+}
+
+// eachElement 按照文档顺序遍历 node 的子孙元素，fn 返回 false 时不再遍历该元素的子节点
+func eachElement(node *html.Node, fn func(*html.Node) bool) {
+	for child := node.FirstChild; child != nil; child = child.NextSibling {
+		if child.Type == html.ElementNode && fn(child) {
+			eachElement(child, fn)
+		}
+	}
+}
+
+// nodeText 返回节点中全部文本节点的内容
+func nodeText(node *html.Node) string {
+	if node.Type == html.TextNode {
+		return node.Data
+	}
+	var buf strings.Builder
+	var f func(*html.Node)
+	f = func(n *html.Node) {
+		for child := n.FirstChild; child != nil; child = child.NextSibling {
+			switch child.Type {
+			case html.TextNode:
+				buf.WriteString(child.Data)
+			case html.ElementNode:
+				if child.DataAtom != atom.Script && child.DataAtom != atom.Style {
+					f(child)
+				}
+			}
+		}
+	}
+	f(node)
+	return buf.String()
+}
+
+func collapseSpace(s string) string {
+	return strings.Join(strings.Fields(s), " ")
+}
+
+func getAttr(node *html.Node, key string) string {
+	val, _ := findAttr(node, key)
+	return val
+}
+
+func findAttr(node *html.Node, key string) (string, bool) {
+	for _, a := range node.Attr {
+		if a.Namespace == "" && a.Key == key {
+			return a.Val, true
+		}
+	}
+	return "", false
+}
@@ -0,0 +1,77 @@
+package html2json
+
+import (
+	"strings"
+	"testing"
+)
+
+const articleHTML = `<html><head>
+<title>如何使用 html2json - 书栈网</title>
+<meta name="author" content="TruthHun">
+<meta property="article:published_time" content="2019-10-01T08:00:00+08:00">
+</head><body>
+<header class="site-header"><a href="/">书栈网</a><nav><a href="/a">首页</a><a href="/b">书籍</a></nav></header>
+<div id="cookie-banner">本站使用 cookie 来提升您的浏览体验，继续浏览即表示您同意我们使用 cookie。</div>
+<div class="container">
+	<div class="sidebar"><ul><li><a href="/1">热门书籍一，点击查看更多内容，这是一个很长的链接文本</a></li><li><a href="/2">热门书籍二</a></li></ul></div>
+	<div class="post-content">
+		<h1>如何使用 html2json</h1>
+		<p>html2json 用于将 HTML 转换为小程序 rich-text 组件可以使用的 JSON，支持微信、支付宝、百度、头条以及 QQ 小程序。</p>
+		<p>转换时会根据小程序信任的标签，将不支持的标签转换为 div，并且为每个标签生成 class，以便于控制样式。</p>
+		<p>除了 HTML 之外，还可以直接转换 markdown 内容，或者获取链接的内容并进行转换，这里只是<a href="/more">更多</a>的说明。</p>
+	</div>
+	<div class="related"><p>相关文章：<a href="/c">如何使用 markdown，以及其他很多很多很多的内容</a></p></div>
+</div>
+<footer>Copyright © 书栈网，保留所有权利，未经许可不得转载本站的任何内容。</footer>
+</body></html>`
+
+func TestRichText_ParseArticle(t *testing.T) {
+	a, err := rt.ParseArticle(articleHTML, "https://www.bookstack.cn")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if a.Title != "如何使用 html2json" || a.Byline != "TruthHun" || a.Published != "2019-10-01T08:00:00+08:00" {
+		t.Errorf("unexpected article: %+v", a)
+	}
+	text := InnerText(a.Nodes)
+	for _, s := range []string{"rich-text 组件", "生成 class", "更多"} {
+		if !strings.Contains(text, s) {
+			t.Errorf("expect %q in article: %v", s, text)
+		}
+	}
+	for _, s := range []string{"首页", "cookie", "热门书籍", "相关文章", "Copyright"} {
+		if strings.Contains(text, s) {
+			t.Errorf("unexpected %q in article: %v", s, text)
+		}
+	}
+	if len(a.Nodes) != 1 || a.Nodes[0].Attrs["class"] != "tag-div post-content" {
+		t.Errorf("unexpected nodes: %v", toJSON(a.Nodes))
+	}
+}
+
+func TestRichText_ParseArticle_meta(t *testing.T) {
+	cases := []struct {
+		html   string
+		expect Article
+	}{
+		{`<title>标题 | 网站</title><p>内容</p>`, Article{Title: "标题"}},
+		{`<meta property="og:title" content="OG 标题"><title>标题 - 网站</title>`, Article{Title: "OG 标题"}},
+		{`<div class="byline">作者： 张三</div><time datetime="2020-01-02">1月2日</time>`, Article{Byline: "作者： 张三", Published: "2020-01-02"}},
+		{`<a rel="author" href="/u/1">李四</a><span itemprop="datePublished" content="2020-03-04">3月4日</span>`, Article{Byline: "李四", Published: "2020-03-04"}},
+	}
+	for _, c := range cases {
+		a, err := rt.ParseArticle(c.html, "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if a.Title != c.expect.Title || a.Byline != c.expect.Byline || a.Published != c.expect.Published {
+			t.Errorf("unexpected article for %v: %+v", c.html, a)
+		}
+	}
+
+	// 找不到正文时转换 body 的全部内容
+	a, _ := rt.ParseArticle(`<p>短内容</p>`, "")
+	if InnerText(a.Nodes) != "短内容" {
+		t.Errorf("unexpected nodes: %v", toJSON(a.Nodes))
+	}
+}
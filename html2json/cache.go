@@ -0,0 +1,272 @@
+package html2json
+
+import (
+	"container/list"
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Cache 缓存 ParseByURL 等方法获取的页面以及转换结果，key 为链接
+type Cache interface {
+	Get(key string) (*CacheEntry, bool)
+	Set(key string, entry *CacheEntry)
+}
+
+// CacheEntry 缓存的页面
+type CacheEntry struct {
+	Body         []byte    `json:"body"` // 未转换编码的内容
+	ContentType  string    `json:"content_type,omitempty"`
+	ETag         string    `json:"etag,omitempty"`
+	LastModified string    `json:"last_modified,omitempty"`
+	Validated    time.Time `json:"validated"` // 最近一次获取或者验证的时间
+	// 不同配置以及转换方式的转换结果，最近使用的在前，最多 maxCacheOutputs 个，页面发生变化时清空
+	Outputs []CacheOutput `json:"outputs,omitempty"`
+}
+
+// CacheOutput 缓存的转换结果，Key 由转换方式以及影响转换结果的配置生成
+type CacheOutput struct {
+	Key    string          `json:"key"`
+	Output json.RawMessage `json:"output"`
+}
+
+// maxCacheOutputs 每个页面最多缓存的转换结果数量
+const maxCacheOutputs = 8
+
+// SetCache 设置缓存，c 为 nil 时不缓存。在 ttl 之内直接使用缓存的页面，超过 ttl 之后使用 ETag 和 Last-Modified 发送条件请求验证，
+// 页面没有变化时继续使用缓存的页面以及转换结果。转换结果按照 selectors、excludes、domain 等影响结果的配置区分，
+// 配置相同的 RichText（包括 Clone 得到的）共用转换结果。ClassFunc、Transform、Engine 等函数类的配置无法比较，
+// 共用同一个 Cache 的 RichText 中这些配置应当保持一致。Cache-Control 为 no-store、private 的响应以及带有 Cookie、
+// Authorization 请求头的请求不会缓存
+func (r *RichText) SetCache(c Cache, ttl time.Duration) *RichText {
+	r.cache, r.cacheTTL = c, ttl
+	return r
+}
+
+// outputKey 根据转换方式以及影响转换结果的配置生成缓存转换结果的 key
+func (r *RichText) outputKey(kind, domain string) string {
+	var tags []string
+	r.tagsMap.Range(func(key, value interface{}) bool {
+		tags = append(tags, key.(string))
+		return true
+	})
+	sort.Strings(tags)
+	var sanitizer interface{}
+	if r.sanitizer != nil {
+		sanitizer = []interface{}{r.sanitizer.Schemes, r.sanitizer.AllowDataImages}
+	}
+	b, _ := json.Marshal([]interface{}{
+		kind, domain, r.selectors, r.excludes, tags, r.attrs, sanitizer, r.classPrefix, fmt.Sprintf("%T", r.engine),
+		r.limits, r.whitespace, r.inlineStyles, r.highlight, r.headingIDs, r.lazyAttrs, r.imageWidth, r.imageDensity,
+		r.charset, r.linkOptions,
+	})
+	sum := sha1.Sum(b)
+	return hex.EncodeToString(sum[:])
+}
+
+// fetchParse 获取链接的内容并转换，页面没有变化时直接使用缓存中相同配置的转换结果，parse 返回的结果需要可以序列化为 JSON。
+// 命中缓存的转换结果并且页面不需要重新验证时不写入缓存
+func (r *RichText) fetchParse(ctx context.Context, urlStr, domain, kind string, out interface{}, parse func(b []byte) (interface{}, error)) error {
+	b, entry, changed, err := r.fetchCached(ctx, urlStr)
+	if err != nil {
+		return err
+	}
+	if entry == nil {
+		_, err = parse(b)
+		return err
+	}
+	defer func() {
+		if changed {
+			r.cache.Set(urlStr, entry)
+		}
+	}()
+	key := r.outputKey(kind, domain)
+	for _, o := range entry.Outputs {
+		if o.Key == key && json.Unmarshal(o.Output, out) == nil {
+			return nil
+		}
+	}
+	v, err := parse(b)
+	if err != nil {
+		return err
+	}
+	if output, err := json.Marshal(v); err == nil {
+		entry.Outputs = append([]CacheOutput{{Key: key, Output: output}}, entry.Outputs...)
+		if len(entry.Outputs) > maxCacheOutputs {
+			entry.Outputs = entry.Outputs[:maxCacheOutputs]
+		}
+		changed = true
+	}
+	return nil
+}
+
+// fetchCached 获取链接的内容并转换为 UTF-8，设置了缓存时优先使用缓存的页面，响应不能缓存时 entry 为 nil，
+// 重新获取或者验证了页面时 changed 为 true，由调用者写入缓存。HTTPFetcher 设置了 Cookie 或者 Authorization 时不使用缓存
+func (r *RichText) fetchCached(ctx context.Context, urlStr string) (b []byte, entry *CacheEntry, changed bool, err error) {
+	if f, ok := r.fetcher.(*HTTPFetcher); r.cache != nil && !(ok && f.credentials()) {
+		if e, ok := r.cache.Get(urlStr); ok {
+			entry = e
+		}
+	}
+	if entry == nil || time.Since(entry.Validated) >= r.cacheTTL {
+		var resp *http.Response
+		if b, resp, err = r.fetch(ctx, urlStr, entry); err != nil {
+			return nil, nil, false, err
+		}
+		switch {
+		case resp.StatusCode == http.StatusNotModified && entry != nil:
+			entry.Validated = time.Now()
+			if etag := resp.Header.Get("ETag"); etag != "" {
+				entry.ETag = etag
+			}
+		case resp.StatusCode < 200 || resp.StatusCode > 299:
+			return nil, nil, false, &StatusError{URL: urlStr, StatusCode: resp.StatusCode}
+		case resp.StatusCode == http.StatusOK && r.cache != nil && cacheable(resp):
+			entry = &CacheEntry{
+				Body:         b,
+				ContentType:  resp.Header.Get("Content-Type"),
+				ETag:         resp.Header.Get("ETag"),
+				LastModified: resp.Header.Get("Last-Modified"),
+				Validated:    time.Now(),
+			}
+		default:
+			b, err = r.decode(b, resp.Header.Get("Content-Type"))
+			return b, nil, false, err
+		}
+		changed = true
+	}
+	b, err = r.decode(entry.Body, entry.ContentType)
+	return b, entry, changed, err
+}
+
+// cacheable 判断响应能否缓存：Cache-Control 为 no-store 或者 private，以及请求带有 Cookie 或者 Authorization 时不缓存，
+// 避免将一个用户的页面返回给其他用户
+func cacheable(resp *http.Response) bool {
+	for _, value := range resp.Header.Values("Cache-Control") {
+		for _, directive := range strings.Split(value, ",") {
+			switch strings.ToLower(strings.TrimSpace(directive)) {
+			case "no-store", "private":
+				return false
+			}
+		}
+	}
+	if req := resp.Request; req != nil && (req.Header.Get("Cookie") != "" || req.Header.Get("Authorization") != "") {
+		return false
+	}
+	return true
+}
+
+// MemoryCache 保存在内存中的缓存，超出数量时淘汰最久未使用的页面
+type MemoryCache struct {
+	size  int
+	mu    sync.Mutex
+	ll    *list.List
+	items map[string]*list.Element
+}
+
+type memoryItem struct {
+	key   string
+	entry CacheEntry
+}
+
+// NewMemoryCache 创建最多保存 size 个页面的内存缓存
+func NewMemoryCache(size int) *MemoryCache {
+	return &MemoryCache{size: size, ll: list.New(), items: make(map[string]*list.Element)}
+}
+
+func (c *MemoryCache) Get(key string) (*CacheEntry, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	elem, ok := c.items[key]
+	if !ok {
+		return nil, false
+	}
+	c.ll.MoveToFront(elem)
+	entry := elem.Value.(*memoryItem).entry
+	return &entry, true
+}
+
+func (c *MemoryCache) Set(key string, entry *CacheEntry) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if elem, ok := c.items[key]; ok {
+		elem.Value.(*memoryItem).entry = *entry
+		c.ll.MoveToFront(elem)
+		return
+	}
+	c.items[key] = c.ll.PushFront(&memoryItem{key: key, entry: *entry})
+	for c.size > 0 && c.ll.Len() > c.size {
+		elem := c.ll.Back()
+		c.ll.Remove(elem)
+		delete(c.items, elem.Value.(*memoryItem).key)
+	}
+}
+
+// Len 返回缓存的页面数量
+func (c *MemoryCache) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.ll.Len()
+}
+
+// DiskCache 保存在目录中的缓存，每个页面一个 JSON 文件，文件名为链接的 SHA1，不会自动清理
+type DiskCache struct {
+	dir string
+}
+
+// NewDiskCache 创建保存在 dir 目录中的缓存，目录不存在时在写入时创建
+func NewDiskCache(dir string) *DiskCache {
+	return &DiskCache{dir: dir}
+}
+
+func (c *DiskCache) path(key string) string {
+	sum := sha1.Sum([]byte(key))
+	return filepath.Join(c.dir, hex.EncodeToString(sum[:])+".json")
+}
+
+func (c *DiskCache) Get(key string) (*CacheEntry, bool) {
+	b, err := ioutil.ReadFile(c.path(key))
+	if err != nil {
+		return nil, false
+	}
+	entry := &CacheEntry{}
+	if err = json.Unmarshal(b, entry); err != nil {
+		return nil, false
+	}
+	return entry, true
+}
+
+// Set 先写入临时文件再重命名，避免读取到不完整的文件，写入失败时忽略
+func (c *DiskCache) Set(key string, entry *CacheEntry) {
+	b, err := json.Marshal(entry)
+	if err != nil {
+		return
+	}
+	if err = os.MkdirAll(c.dir, 0755); err != nil {
+		return
+	}
+	f, err := ioutil.TempFile(c.dir, ".tmp-*")
+	if err != nil {
+		return
+	}
+	_, err = f.Write(b)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(f.Name(), c.path(key))
+	}
+	if err != nil {
+		os.Remove(f.Name())
+	}
+}
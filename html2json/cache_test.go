@@ -0,0 +1,188 @@
+package html2json
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestRichText_SetCache(t *testing.T) {
+	var requests, modified int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		requests++
+		etag := `"v1"`
+		if req.URL.Path == "/changed" {
+			etag = `"v2"`
+		}
+		if req.Header.Get("If-None-Match") == etag {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		modified++
+		w.Header().Set("ETag", etag)
+		w.Write([]byte("<p>hello</p>"))
+	}))
+	defer server.Close()
+	f := NewFetcher()
+	f.Policy.AllowPrivate = true
+
+	for _, c := range []Cache{NewMemoryCache(10), NewDiskCache(t.TempDir())} {
+		requests, modified = 0, 0
+		cache := &countCache{Cache: c}
+		r := NewDefault().SetClassPrefix("").SetFetcher(f).SetCache(cache, time.Hour)
+		for i := 0; i < 2; i++ {
+			nodes, err := r.ParseByURL(server.URL+"/page", "")
+			if err != nil || InnerText(nodes) != "hello" {
+				t.Errorf("unexpected nodes: %v %v", toJSON(nodes), err)
+			}
+		}
+		// 有效期之内不发送请求，命中转换结果时不写入缓存
+		if requests != 1 || cache.sets != 1 {
+			t.Errorf("unexpected requests %v, sets %v", requests, cache.sets)
+		}
+		entry, ok := cache.Get(server.URL + "/page")
+		if !ok || entry.ETag != `"v1"` || len(entry.Outputs) != 1 {
+			t.Fatalf("unexpected entry: %+v", entry)
+		}
+
+		// 过期之后发送条件请求，返回 304 时复用转换结果
+		entry.Validated = time.Now().Add(-2 * time.Hour)
+		entry.Outputs[0].Output = []byte(`[{"type":"text","text":"cached"}]`)
+		cache.Set(server.URL+"/page", entry)
+		nodes, err := r.ParseByURL(server.URL+"/page", "")
+		if err != nil || requests != 2 || modified != 1 || InnerText(nodes) != "cached" {
+			t.Errorf("unexpected revalidation: %v %v %v %v", requests, modified, toJSON(nodes), err)
+		}
+		if entry, _ = cache.Get(server.URL + "/page"); time.Since(entry.Validated) > time.Minute {
+			t.Errorf("unexpected validated time %v", entry.Validated)
+		}
+
+		// 不同的转换方式以及配置不复用转换结果，相同配置的 RichText 复用转换结果
+		result, err := r.ParseResultByURL(server.URL+"/page", "")
+		if err != nil || InnerText(result.Nodes) != "hello" || requests != 2 {
+			t.Errorf("unexpected result: %v %v", toJSON(result), err)
+		}
+		if nodes, err = r.Clone().SetSelectors("p").ParseByURL(server.URL+"/page", ""); err != nil || InnerText(nodes) != "hello" || requests != 2 {
+			t.Errorf("unexpected nodes: %v %v", toJSON(nodes), err)
+		}
+		sets := cache.sets
+		for i := 0; i < 2; i++ {
+			if nodes, err = r.Clone().ParseByURL(server.URL+"/page", ""); err != nil || InnerText(nodes) != "cached" {
+				t.Errorf("unexpected nodes: %v %v", toJSON(nodes), err)
+			}
+			if result, err = r.ParseResultByURL(server.URL+"/page", ""); err != nil || InnerText(result.Nodes) != "hello" {
+				t.Errorf("unexpected result: %v %v", toJSON(result), err)
+			}
+		}
+		if entry, _ = cache.Get(server.URL + "/page"); len(entry.Outputs) != 3 || cache.sets != sets || requests != 2 {
+			t.Errorf("unexpected outputs %v, sets %v, requests %v", len(entry.Outputs), cache.sets-sets, requests)
+		}
+
+		// 页面变化时重新转换
+		entry, _ = cache.Get(server.URL + "/page")
+		entry.Validated = time.Time{}
+		cache.Set(server.URL+"/changed", entry)
+		if nodes, err = r.ParseByURL(server.URL+"/changed", ""); err != nil || InnerText(nodes) != "hello" || modified != 2 {
+			t.Errorf("unexpected nodes: %v %v", toJSON(nodes), err)
+		}
+		if entry, _ = cache.Get(server.URL + "/changed"); entry.ETag != `"v2"` {
+			t.Errorf("unexpected etag %v", entry.ETag)
+		}
+	}
+}
+
+func TestRichText_SetCache_private(t *testing.T) {
+	var requests int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		requests++
+		switch req.URL.Path {
+		case "/private":
+			w.Header().Set("Cache-Control", "max-age=60, Private")
+		case "/no-store":
+			w.Header().Set("Cache-Control", "no-store")
+		}
+		w.Header().Set("ETag", `"v1"`)
+		w.Write([]byte("<p>hello</p>"))
+	}))
+	defer server.Close()
+	anonymous := NewFetcher()
+	anonymous.Policy.AllowPrivate = true
+	cookie := NewFetcher()
+	cookie.Policy.AllowPrivate = true
+	cookie.Cookies = []*http.Cookie{{Name: "session", Value: "a"}}
+	auth := NewFetcher()
+	auth.Policy.AllowPrivate = true
+	auth.Header.Set("Authorization", "Bearer a")
+	// 通过 http.Client 发送的请求同样检查请求头
+	client := FetcherFunc(func(req *http.Request) (*http.Response, error) {
+		req.Header.Set("Cookie", "session=a")
+		return http.DefaultClient.Do(req)
+	})
+
+	tests := []struct {
+		path    string
+		fetcher Fetcher
+	}{
+		{"/private", anonymous},
+		{"/no-store", anonymous},
+		{"/page", cookie},
+		{"/page", auth},
+		{"/page", client},
+	}
+	for _, tt := range tests {
+		requests = 0
+		cache := &countCache{Cache: NewMemoryCache(10)}
+		r := NewDefault().SetFetcher(tt.fetcher).SetCache(cache, time.Hour)
+		for i := 0; i < 2; i++ {
+			if nodes, err := r.ParseByURL(server.URL+tt.path, ""); err != nil || InnerText(nodes) != "hello" {
+				t.Errorf("%v: unexpected nodes: %v %v", tt.path, toJSON(nodes), err)
+			}
+		}
+		if _, ok := cache.Get(server.URL + tt.path); ok || cache.sets != 0 || requests != 2 {
+			t.Errorf("%v: unexpected cache %v, sets %v, requests %v", tt.path, ok, cache.sets, requests)
+		}
+	}
+
+	// 带有 cookie 的请求不使用其他请求缓存的页面
+	cache := NewMemoryCache(10)
+	r := NewDefault().SetFetcher(anonymous).SetCache(cache, time.Hour)
+	if _, err := r.ParseByURL(server.URL+"/page", ""); err != nil {
+		t.Fatal(err)
+	}
+	requests = 0
+	if _, err := r.Clone().SetFetcher(cookie).ParseByURL(server.URL+"/page", ""); err != nil || requests != 1 {
+		t.Errorf("unexpected requests %v %v", requests, err)
+	}
+}
+
+// countCache 记录写入缓存的次数
+type countCache struct {
+	Cache
+	sets int
+}
+
+func (c *countCache) Set(key string, entry *CacheEntry) {
+	c.sets++
+	c.Cache.Set(key, entry)
+}
+
+func TestMemoryCache(t *testing.T) {
+	c := NewMemoryCache(2)
+	c.Set("a", &CacheEntry{ETag: "a"})
+	c.Set("b", &CacheEntry{ETag: "b"})
+	c.Get("a")
+	c.Set("c", &CacheEntry{ETag: "c"})
+	if _, ok := c.Get("b"); ok || c.Len() != 2 {
+		t.Errorf("expect b to be evicted, len %v", c.Len())
+	}
+	entry, ok := c.Get("a")
+	if !ok || entry.ETag != "a" {
+		t.Errorf("unexpected entry %+v", entry)
+	}
+	// 修改返回的 entry 不影响缓存
+	entry.ETag = "x"
+	if entry, _ = c.Get("a"); entry.ETag != "a" {
+		t.Errorf("unexpected entry %+v", entry)
+	}
+}
@@ -0,0 +1,133 @@
+package html2json
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+	"unicode/utf8"
+
+	"golang.org/x/net/html/charset"
+	"golang.org/x/text/encoding/charmap"
+)
+
+// SetCharset 指定 HTML 内容的字符集，如 gbk、big5，用于代替 Content-Type、<meta charset> 以及内容识别。
+// 为空时自动检测，见 DetectCharset。带有 BOM 或者是合法 UTF-8 的内容总是作为 UTF-8(或 BOM 对应的字符集)处理
+func (r *RichText) SetCharset(name string) *RichText {
+	r.charset = strings.TrimSpace(name)
+	return r
+}
+
+var (
+	utf8BOM    = []byte{0xEF, 0xBB, 0xBF}
+	utf16BEBOM = []byte{0xFE, 0xFF}
+	utf16LEBOM = []byte{0xFF, 0xFE}
+)
+
+// 用于识别 GBK 与 Big5 的常用汉字，包括简体和繁体
+const commonHan = "的一是不了在人有我他这這中大来來上国國个個到说說们們为為子和你地出道也时時年得就那要下以生会會自着著去之过過家学學对對可她里裡后後小么麼心多天而能好都然没沒日于於起还還发發成事只作当當想看文无無开開手十用主行方又如前所本见見经經头頭面公同三已老从從动動两兩长長"
+
+// DetectCharset 检测 HTML 内容的字符集，返回 WHATWG 规范中的名称，如 utf-8、gbk、big5。
+// 依次根据 BOM、内容是否为合法的 UTF-8、contentType 中的 charset、<meta charset> 以及 <meta http-equiv="Content-Type"> 判断，
+// 都没有时根据常用汉字出现的次数在 GBK 和 Big5 之间选择，没有汉字时为 windows-1252
+func DetectCharset(content []byte, contentType string) string {
+	switch {
+	case bytes.HasPrefix(content, utf8BOM):
+		return "utf-8"
+	case bytes.HasPrefix(content, utf16BEBOM):
+		return "utf-16be"
+	case bytes.HasPrefix(content, utf16LEBOM):
+		return "utf-16le"
+	case utf8.Valid(content):
+		return "utf-8"
+	}
+	// 没有声明字符集时 DetermineEncoding 返回 charmap.Windows1252
+	if e, name, certain := charset.DetermineEncoding(content, contentType); certain || e != charmap.Windows1252 {
+		return name
+	}
+	return sniffCharset(content)
+}
+
+// sniffCharset 分别使用 GBK 和 Big5 解码内容的前 64KB，选择常用汉字更多的字符集
+func sniffCharset(content []byte) string {
+	if len(content) > 64<<10 {
+		content = content[:64<<10]
+	}
+	best, score := "windows-1252", 0
+	for _, name := range []string{"gbk", "big5"} {
+		e, _ := charset.Lookup(name)
+		b, err := e.NewDecoder().Bytes(content)
+		if err != nil {
+			continue
+		}
+		n := 0
+		for _, c := range string(b) {
+			if c >= 0x4E00 && strings.ContainsRune(commonHan, c) {
+				n++
+			}
+		}
+		if n > score {
+			best, score = name, n
+		}
+	}
+	return best
+}
+
+// decode 将 HTML 内容转换为 UTF-8，contentType 为 HTTP 响应的 Content-Type
+func (r *RichText) decode(content []byte, contentType string) ([]byte, error) {
+	name := r.charset
+	if name == "" || hasBOM(content) || utf8.Valid(content) {
+		name = DetectCharset(content, contentType)
+	}
+	e, name := charset.Lookup(name)
+	if e == nil {
+		return nil, fmt.Errorf("unsupported charset: %v", r.charset)
+	}
+	if name == "utf-8" && utf8.Valid(content) {
+		return bytes.TrimPrefix(content, utf8BOM), nil
+	}
+	return e.NewDecoder().Bytes(content)
+}
+
+// decodeReader 与 decode 一致，将读取的内容转换为 UTF-8，用于 Encode，根据开头的 1024 字节检测字符集
+func (r *RichText) decodeReader(rd io.Reader) (io.Reader, error) {
+	br := bufio.NewReaderSize(rd, 1024)
+	head, err := br.Peek(1024)
+	switch err {
+	case nil:
+		head = completeRunes(head)
+	case io.EOF:
+	default:
+		return nil, err
+	}
+	name := r.charset
+	if name == "" || hasBOM(head) || utf8.Valid(head) {
+		name = DetectCharset(head, "")
+	}
+	e, name := charset.Lookup(name)
+	if e == nil {
+		return nil, fmt.Errorf("unsupported charset: %v", r.charset)
+	}
+	if name == "utf-8" && bytes.HasPrefix(head, utf8BOM) {
+		br.Discard(len(utf8BOM))
+	}
+	return e.NewDecoder().Reader(br), nil
+}
+
+// completeRunes 去掉末尾被截断的 UTF-8 字符
+func completeRunes(b []byte) []byte {
+	for i := len(b) - 1; i >= 0 && i >= len(b)-utf8.UTFMax; i-- {
+		if utf8.RuneStart(b[i]) {
+			if !utf8.FullRune(b[i:]) {
+				return b[:i]
+			}
+			break
+		}
+	}
+	return b
+}
+
+func hasBOM(content []byte) bool {
+	return bytes.HasPrefix(content, utf8BOM) || bytes.HasPrefix(content, utf16BEBOM) || bytes.HasPrefix(content, utf16LEBOM)
+}
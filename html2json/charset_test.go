@@ -0,0 +1,73 @@
+package html2json
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"golang.org/x/net/html/charset"
+)
+
+func encodeString(t *testing.T, name, s string) []byte {
+	e, _ := charset.Lookup(name)
+	b, err := e.NewEncoder().Bytes([]byte(s))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+func TestDetectCharset(t *testing.T) {
+	text := "<p>这是一个测试页面，我们在这里说明中文的编码。</p>"
+	traditional := "<p>這是一個測試頁面，我們在這裡說明中文的編碼。</p>"
+	cases := []struct {
+		content     []byte
+		contentType string
+		expect      string
+	}{
+		{[]byte(text), "", "utf-8"},
+		{append([]byte{0xEF, 0xBB, 0xBF}, text...), "text/html; charset=gbk", "utf-8"},
+		{encodeString(t, "gbk", text), "text/html; charset=gb2312", "gbk"},
+		{encodeString(t, "gbk", `<meta charset="gb2312">`+text), "", "gbk"},
+		{encodeString(t, "big5", `<meta http-equiv="Content-Type" content="text/html; charset=big5">`+traditional), "", "big5"},
+		{encodeString(t, "gbk", text), "", "gbk"},
+		{encodeString(t, "big5", traditional), "", "big5"},
+		{[]byte("<p>caf\xe9</p>"), "", "windows-1252"},
+	}
+	for i, c := range cases {
+		if got := DetectCharset(c.content, c.contentType); got != c.expect {
+			t.Errorf("case %v: unexpected charset %v, expect %v", i, got, c.expect)
+		}
+	}
+}
+
+func TestRichText_decode(t *testing.T) {
+	text := "这是一个测试页面"
+	expect := `[{"name":"p","children":[{"type":"text","text":"` + text + `"}]}]`
+	r := NewDefault().SetClassPrefix("")
+	nodes, err := r.ParseByByte(encodeString(t, "gbk", "<p>"+text+"</p>"), "")
+	if err != nil || toJSON(nodes) != expect {
+		t.Errorf("unexpected nodes: %v %v", toJSON(nodes), err)
+	}
+
+	// 指定的字符集不影响 UTF-8 的内容
+	r.SetCharset("big5")
+	if nodes, err = r.ParseByByte([]byte("<p>"+text+"</p>"), ""); err != nil || toJSON(nodes) != expect {
+		t.Errorf("unexpected nodes: %v %v", toJSON(nodes), err)
+	}
+	if _, err = r.SetCharset("unknown").ParseByByte([]byte("<p>\xff</p>"), ""); err == nil {
+		t.Error("expect error of unsupported charset")
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=GB2312")
+		w.Write(encodeString(t, "gbk", "<p>"+text+"</p>"))
+	}))
+	defer server.Close()
+	f := NewFetcher()
+	f.Policy.AllowPrivate = true
+	nodes, err = r.SetCharset("").SetFetcher(f).ParseByURL(server.URL, "")
+	if err != nil || toJSON(nodes) != expect {
+		t.Errorf("unexpected nodes: %v %v", toJSON(nodes), err)
+	}
+}
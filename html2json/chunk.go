@@ -0,0 +1,124 @@
+package html2json
+
+import (
+	"encoding/json"
+	"strconv"
+)
+
+// Chunk 将节点按顺序分为多块，使每一块序列化为 JSON 数组之后不超过 size 字节，
+// 可用于分多次调用小程序的 setData。超出大小的节点会在子节点的边界处拆分为多个节点，每个节点都保留原有的外层元素，
+// 拆分后的 ol 会修改 start 属性以保持编号连续。无法拆分的文本节点以及没有子节点的元素超出大小时单独作为一块
+func Chunk(nodes []Node, size int) [][]Node {
+	c := &chunker{size: size}
+	for _, node := range nodes {
+		c.add(node)
+	}
+	c.flush()
+	return c.chunks
+}
+
+type chunker struct {
+	size    int
+	chunks  [][]Node
+	current []Node
+	used    int // current 序列化之后的大小
+}
+
+func jsonSize(v interface{}) int {
+	b, _ := json.Marshal(v)
+	return len(b)
+}
+
+// fits 判断大小为 n 的节点能否加入当前块
+func (c *chunker) fits(n int) bool {
+	if len(c.current) == 0 {
+		return 2+n <= c.size
+	}
+	return c.used+1+n <= c.size
+}
+
+func (c *chunker) push(node Node, n int) {
+	if len(c.current) == 0 {
+		c.used = 2 + n
+	} else {
+		c.used += 1 + n
+	}
+	c.current = append(c.current, node)
+}
+
+func (c *chunker) flush() {
+	if len(c.current) > 0 {
+		c.chunks = append(c.chunks, c.current)
+		c.current, c.used = nil, 0
+	}
+}
+
+func (c *chunker) add(node Node) {
+	n := jsonSize(node)
+	if c.fits(n) {
+		c.push(node, n)
+		return
+	}
+	c.flush()
+	if c.fits(n) || len(node.Children) == 0 {
+		c.push(node, n)
+		return
+	}
+
+	// 外层元素的大小，不包括子节点
+	wrapper := node
+	wrapper.Children = nil
+	overhead := jsonSize(wrapper)
+	start, items := 1, 0
+	if node.Name == "ol" {
+		if s, err := strconv.Atoi(node.Attrs["start"]); err == nil {
+			start = s
+		}
+		// 拆分后的 ol 会设置 start 属性，按照最长的 start 计算
+		end := start
+		for _, child := range node.Children {
+			if child.Name == "li" {
+				end++
+			}
+		}
+		for _, s := range []int{start, end} {
+			measure := wrapper
+			measure.Attrs = withStart(node.Attrs, s)
+			if n := jsonSize(measure); n > overhead {
+				overhead = n
+			}
+		}
+	}
+	budget := c.size - 2 - overhead - len(`,"children":`)
+	if budget < 2 {
+		budget = 2
+	}
+	for i, group := range Chunk(node.Children, budget) {
+		piece := wrapper
+		piece.Children = group
+		if node.Name == "ol" && i > 0 {
+			piece.Attrs = withStart(node.Attrs, start+items)
+		}
+		for _, child := range group {
+			if child.Name == "li" {
+				items++
+			}
+		}
+		// 拆分后的节点不再继续拆分，避免无法拆分的子节点导致无限循环
+		n = jsonSize(piece)
+		if !c.fits(n) {
+			c.flush()
+		}
+		c.push(piece, n)
+	}
+}
+
+// withStart 复制 attrs 并设置 start 属性
+func withStart(attrs map[string]string, start int) map[string]string {
+	m := make(map[string]string, len(attrs)+1)
+	for key, val := range attrs {
+		m[key] = val
+	}
+	m["start"] = strconv.Itoa(start)
+	return m
+}
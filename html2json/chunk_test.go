@@ -0,0 +1,89 @@
+package html2json
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"strings"
+	"testing"
+)
+
+func TestChunk(t *testing.T) {
+	b, err := ioutil.ReadFile("examples/uniapp.html")
+	if err != nil {
+		t.Fatal(err)
+	}
+	nodes, err := rt.ParseByByte(b, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, size := range []int{512, 4096, 64 << 10, 1 << 30} {
+		chunks := Chunk(nodes, size)
+		var text strings.Builder
+		for _, chunk := range chunks {
+			if n := jsonSize(chunk); n > size && !(len(chunk) == 1 && unsplittable(chunk[0])) {
+				t.Errorf("chunk size %v exceeds %v", n, size)
+			}
+			text.WriteString(InnerText(chunk))
+		}
+		if text.String() != InnerText(nodes) {
+			t.Errorf("text of chunks mismatch with size %v", size)
+		}
+	}
+	if chunks := Chunk(nodes, 1<<30); len(chunks) != 1 || jsonSize(chunks[0]) != jsonSize(nodes) {
+		t.Errorf("unexpected chunks: %v", len(chunks))
+	}
+	if chunks := Chunk(nil, 1024); len(chunks) != 0 {
+		t.Errorf("unexpected chunks: %v", len(chunks))
+	}
+}
+
+// unsplittable 判断超出大小的节点是否只能单独作为一块：拆分到最后只剩一个无法拆分的子节点
+func unsplittable(node Node) bool {
+	if len(node.Children) == 0 {
+		return true
+	}
+	return len(node.Children) == 1 && unsplittable(node.Children[0])
+}
+
+func TestChunk_wrappers(t *testing.T) {
+	htmlStr := `<div class="a"><ol start="3"><li>one</li><li>two</li><li>three</li></ol></div><p>four</p>`
+	nodes, _ := rt.Clone().SetClassPrefix("").Parse(htmlStr, "")
+	chunks := Chunk(nodes, 170)
+	expect := []string{
+		`[{"name":"div","attrs":{"class":"a"},"children":[{"name":"ol","attrs":{"start":"3"},"children":[{"name":"li","children":[{"type":"text","text":"one"}]}]}]}]`,
+		`[{"name":"div","attrs":{"class":"a"},"children":[{"name":"ol","attrs":{"start":"4"},"children":[{"name":"li","children":[{"type":"text","text":"two"}]}]}]}]`,
+		`[{"name":"div","attrs":{"class":"a"},"children":[{"name":"ol","attrs":{"start":"5"},"children":[{"name":"li","children":[{"type":"text","text":"three"}]}]}]}]`,
+		`[{"name":"p","children":[{"type":"text","text":"four"}]}]`,
+	}
+	if len(chunks) != len(expect) {
+		t.Fatalf("unexpected chunks: %v", toJSON(chunks))
+	}
+	for i, chunk := range chunks {
+		b, _ := json.Marshal(chunk)
+		if string(b) != expect[i] {
+			t.Errorf("unexpected chunk %v:\n%s\n%v", i, b, expect[i])
+		}
+		if len(b) > 170 {
+			t.Errorf("chunk %v exceeds size: %v", i, len(b))
+		}
+	}
+	// 原有的节点不受影响
+	if nodes[0].Children[0].Attrs["start"] != "3" || len(nodes[0].Children[0].Children) != 3 {
+		t.Errorf("nodes are modified: %v", toJSON(nodes))
+	}
+
+	// 拆分 ol 时计入 start 属性的大小
+	htmlStr = "<ol>" + strings.Repeat("<li>item</li>", 30) + "</ol>"
+	nodes, _ = rt.Clone().SetClassPrefix("").Parse(htmlStr, "")
+	for _, size := range []int{142, 200, 512} {
+		chunks = Chunk(nodes, size)
+		if len(chunks) < 2 {
+			t.Errorf("expect ol to be split with size %v", size)
+		}
+		for i, chunk := range chunks {
+			if n := jsonSize(chunk); n > size {
+				t.Errorf("chunk %v exceeds size %v: %v", i, size, n)
+			}
+		}
+	}
+}
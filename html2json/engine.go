@@ -0,0 +1,89 @@
+package html2json
+
+import (
+	"strings"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// Engine 解析引擎
+type Engine int
+
+const (
+	// EngineGoquery 基于 goquery 逐个节点构造 Selection 进行解析，为默认的解析引擎
+	EngineGoquery Engine = iota
+	// EngineNative 直接遍历 golang.org/x/net/html 的节点树，单次遍历完成转换，内存分配更少，输出与 EngineGoquery 一致
+	EngineNative
+)
+
+// SetEngine 设置解析引擎
+func (r *RichText) SetEngine(engine Engine) *RichText {
+	r.engine = engine
+	return r
+}
+
+// walk 转换 parent 的子节点，depth 为子节点所在的层级。与 converter.parse 的规则一致：
+// 没有子元素时，全部文本合并为一个文本节点，否则每个文本节点单独输出
+func (c *converter) walk(parent *html.Node, depth int) (data []Node) {
+	if c.err != nil {
+		return
+	}
+
+	hasElement := false
+	for item := parent.FirstChild; item != nil; item = item.NextSibling {
+		if item.Type == html.ElementNode {
+			hasElement = true
+			break
+		}
+	}
+
+	if !hasElement {
+		var buf strings.Builder
+		for item := parent.FirstChild; item != nil; item = item.NextSibling {
+			if item.Type == html.TextNode {
+				buf.WriteString(item.Data)
+			}
+		}
+		if buf.Len() > 0 {
+			h := Node{Text: buf.String(), Type: "text"}
+			if c.count(h, depth) {
+				data = []Node{h}
+			}
+		}
+		return
+	}
+
+	for item := parent.FirstChild; item != nil && c.err == nil; item = item.NextSibling {
+		switch item.Type {
+		case html.ElementNode:
+			h, tag, ok := c.element(item)
+			if !ok || !c.count(h, depth) || !c.countNodes(h.Children, depth+1) {
+				continue
+			}
+			if len(h.Children) == 0 {
+				h.Children = c.walk(item, depth+1)
+			}
+			data = append(data, c.r.transform(h, item, tag)...)
+		case html.TextNode:
+			h := Node{Type: "text", Text: item.Data}
+			if c.count(h, depth) {
+				data = append(data, h)
+			}
+		}
+	}
+	return
+}
+
+// findElement 深度优先查找第一个指定的元素
+func findElement(node *html.Node, a atom.Atom) *html.Node {
+	if node.Type == html.ElementNode && node.DataAtom == a {
+		return node
+	}
+	for item := node.FirstChild; item != nil; item = item.NextSibling {
+		if found := findElement(item, a); found != nil {
+			return found
+		}
+	}
+	return nil
+}
@@ -0,0 +1,54 @@
+package html2json
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// 两种解析引擎在示例文件上的输出应当完全一致
+func TestEngineNative(t *testing.T) {
+	native := NewDefault().SetEngine(EngineNative)
+	files, _ := filepath.Glob("examples/*.html")
+	mds, _ := filepath.Glob("examples/*.md")
+	for _, file := range append(files, mds...) {
+		b, err := ioutil.ReadFile(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var expect, got interface{}
+		var err1, err2 error
+		if strings.HasSuffix(file, ".md") {
+			expect, err1 = rt.ParseMarkdownByByte(b, "https://www.bookstack.cn/static/")
+			got, err2 = native.ParseMarkdownByByte(b, "https://www.bookstack.cn/static/")
+		} else {
+			expect, err1 = rt.ParseByByte(b, "https://www.bookstack.cn/static/")
+			got, err2 = native.ParseByByte(b, "https://www.bookstack.cn/static/")
+		}
+		if err1 != nil || err2 != nil {
+			t.Fatal(err1, err2)
+		}
+		if toJSON(expect) != toJSON(got) {
+			t.Errorf("%v: output of native engine mismatch", file)
+		}
+
+		if !strings.HasSuffix(file, ".md") {
+			expect, _ = rt.ParseByByteV2(b, "")
+			got, _ = native.ParseByByteV2(b, "")
+			if toJSON(expect) != toJSON(got) {
+				t.Errorf("%v: output of native engine mismatch in v2", file)
+			}
+		}
+	}
+}
+
+func BenchmarkParse_uniapp_native(b *testing.B) {
+	native := NewDefault().SetEngine(EngineNative)
+	h, _ := ioutil.ReadFile("examples/uniapp.html")
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		native.ParseByByte(h, "")
+	}
+}
@@ -0,0 +1,233 @@
+package html2json
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+)
+
+// DefaultUserAgent NewFetcher 默认使用的 User-Agent
+const DefaultUserAgent = "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/76.0.3809.87 Safari/537.36"
+
+// ErrTooManyRedirects 重定向次数超出 HTTPFetcher.MaxRedirects
+var ErrTooManyRedirects = errors.New("html2json: too many redirects")
+
+// StatusError 获取链接内容时响应的状态码不是 2xx，重试之后仍然失败的 5xx 响应同样返回该错误
+type StatusError struct {
+	URL        string
+	StatusCode int
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("html2json: %v responded with status %v", e.URL, e.StatusCode)
+}
+
+// Fetcher 发送 ParseByURL 等方法获取链接内容的请求，*http.Client 以及 HTTPFetcher 都实现了该接口，
+// 测试时可以使用 FetcherFunc 返回固定的内容
+type Fetcher interface {
+	Do(req *http.Request) (*http.Response, error)
+}
+
+// FetcherFunc 将函数转换为 Fetcher
+type FetcherFunc func(req *http.Request) (*http.Response, error)
+
+func (f FetcherFunc) Do(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+// HTTPFetcher 基于 net/http 的 Fetcher，第一次请求之后再修改字段不会生效
+type HTTPFetcher struct {
+	Header             http.Header                           // 请求头，不会覆盖请求中已有的请求头
+	Cookies            []*http.Cookie                        // 每次请求都会携带的 cookie
+	Proxy              func(*http.Request) (*url.URL, error) // 为 nil 时不使用代理，可以使用 http.ProxyURL 指定代理
+	InsecureSkipVerify bool                                  // 是否跳过 HTTPS 证书校验
+	MaxBodyBytes       int64                                 // 响应内容的最大字节数，为 0 时不限制，超出时返回 LimitError
+	MaxRedirects       int                                   // 最多跟随的重定向次数，超出时返回 ErrTooManyRedirects，为 0 时不跟随，ParseByURL 等方法返回 StatusError
+	Retries            int                                   // 网络错误以及 429、5xx 响应的重试次数，只对 GET 和 HEAD 请求生效
+	RetryBackoff       time.Duration                         // 第一次重试之前的等待时间，之后每次重试翻倍
+	// 网络访问策略，为 nil 时不做限制。每次请求以及重定向之前检查链接，建立连接时检查 DNS 解析之后的 IP，
+	// 使用代理时还会检查链接域名解析得到的 IP，代理服务器的地址需要满足策略，如通过 AllowCIDRs 放行
+	Policy *NetPolicy
+
+	once   sync.Once
+	client *http.Client
+}
+
+// NewFetcher 创建默认的 HTTPFetcher：校验 HTTPS 证书，使用环境变量中的代理，最多跟随 10 次重定向，失败时最多重试 2 次，
+// 并且禁止访问私有、回环、链路本地等地址
+func NewFetcher() *HTTPFetcher {
+	return &HTTPFetcher{
+		Header:       http.Header{"User-Agent": {DefaultUserAgent}},
+		Proxy:        http.ProxyFromEnvironment,
+		MaxRedirects: 10,
+		Retries:      2,
+		RetryBackoff: 500 * time.Millisecond,
+		Policy:       &NetPolicy{},
+	}
+}
+
+// defaultFetcher 未设置 Fetcher 时使用，在多个 RichText 之间复用连接
+var defaultFetcher = NewFetcher()
+
+// SetFetcher 设置获取链接内容使用的 Fetcher，为 nil 时使用 NewFetcher 创建的默认实现
+func (r *RichText) SetFetcher(f Fetcher) *RichText {
+	r.fetcher = f
+	return r
+}
+
+func (f *HTTPFetcher) init() {
+	dialer := &net.Dialer{
+		Timeout:   30 * time.Second,
+		KeepAlive: 30 * time.Second,
+	}
+	if f.Policy != nil {
+		dialer.Control = f.Policy.control
+	}
+	f.client = &http.Client{
+		Transport: &http.Transport{
+			Proxy:                 f.Proxy,
+			DialContext:           dialer.DialContext,
+			TLSClientConfig:       &tls.Config{InsecureSkipVerify: f.InsecureSkipVerify},
+			MaxIdleConns:          100,
+			IdleConnTimeout:       90 * time.Second,
+			TLSHandshakeTimeout:   10 * time.Second,
+			ExpectContinueTimeout: time.Second,
+		},
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			if f.MaxRedirects <= 0 {
+				return http.ErrUseLastResponse
+			}
+			if len(via) > f.MaxRedirects {
+				return ErrTooManyRedirects
+			}
+			return f.check(req)
+		},
+	}
+}
+
+// check 根据网络访问策略检查请求的链接
+func (f *HTTPFetcher) check(req *http.Request) error {
+	if f.Policy == nil {
+		return nil
+	}
+	if err := f.Policy.CheckURL(req.URL); err != nil {
+		return err
+	}
+	if f.Proxy != nil {
+		if proxy, _ := f.Proxy(req); proxy != nil && net.ParseIP(req.URL.Hostname()) == nil {
+			return f.Policy.resolve(req.Context(), req.URL.Hostname())
+		}
+	}
+	return nil
+}
+
+// credentials 判断请求是否带有 Cookie 或者 Authorization
+func (f *HTTPFetcher) credentials() bool {
+	return len(f.Cookies) > 0 || f.Header.Get("Cookie") != "" || f.Header.Get("Authorization") != ""
+}
+
+func (f *HTTPFetcher) Do(req *http.Request) (resp *http.Response, err error) {
+	f.once.Do(f.init)
+	for key, values := range f.Header {
+		if req.Header.Get(key) == "" {
+			for _, val := range values {
+				req.Header.Add(key, val)
+			}
+		}
+	}
+	for _, cookie := range f.Cookies {
+		req.AddCookie(cookie)
+	}
+	if err = f.check(req); err != nil {
+		return
+	}
+
+	ctx := req.Context()
+	for i := 0; ; i++ {
+		resp, err = f.client.Do(req)
+		if i >= f.Retries || !retryable(req, resp, err) {
+			break
+		}
+		if resp != nil {
+			resp.Body.Close()
+		}
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(f.RetryBackoff << uint(i)):
+		}
+	}
+	if err != nil {
+		return
+	}
+
+	if f.MaxBodyBytes > 0 {
+		if resp.ContentLength > f.MaxBodyBytes {
+			resp.Body.Close()
+			return nil, &LimitError{Limit: "body", Max: f.MaxBodyBytes}
+		}
+		resp.Body = struct {
+			io.Reader
+			io.Closer
+		}{&limitReader{reader: resp.Body, max: f.MaxBodyBytes, limit: "body"}, resp.Body}
+	}
+	return
+}
+
+// retryable 判断请求是否可以重试：GET 和 HEAD 请求的网络错误以及 429、5xx 响应，证书错误、域名不存在以及违反网络访问策略时不会重试
+func retryable(req *http.Request, resp *http.Response, err error) bool {
+	if req.Method != http.MethodGet && req.Method != http.MethodHead {
+		return false
+	}
+	if err != nil {
+		var (
+			opErr     *net.OpError
+			dnsErr    *net.DNSError
+			policyErr *PolicyError
+		)
+		if errors.As(err, &dnsErr) && dnsErr.IsNotFound || errors.As(err, &policyErr) {
+			return false
+		}
+		return req.Context().Err() == nil && (errors.As(err, &opErr) || errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF))
+	}
+	return resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500
+}
+
+// fetch 获取链接的 HTML 内容，entry 不为 nil 时发送条件请求，返回未转换编码的内容
+func (r *RichText) fetch(ctx context.Context, urlStr string, entry *CacheEntry) (b []byte, resp *http.Response, err error) {
+	var req *http.Request
+	if req, err = http.NewRequest(http.MethodGet, urlStr, nil); err != nil {
+		return
+	}
+	if entry != nil {
+		if entry.ETag != "" {
+			req.Header.Set("If-None-Match", entry.ETag)
+		}
+		if entry.LastModified != "" {
+			req.Header.Set("If-Modified-Since", entry.LastModified)
+		}
+	}
+	fetcher := r.fetcher
+	if fetcher == nil {
+		fetcher = defaultFetcher
+	}
+	if resp, err = fetcher.Do(req.WithContext(ctx)); err != nil {
+		return nil, nil, canceled(ctx, err)
+	}
+	// 判断能否缓存时需要检查发送的请求
+	if resp.Request == nil {
+		resp.Request = req
+	}
+	defer resp.Body.Close()
+	if b, err = r.limits.readAll(resp.Body); err != nil {
+		return nil, nil, canceled(ctx, err)
+	}
+	return
+}
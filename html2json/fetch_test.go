@@ -0,0 +1,108 @@
+package html2json
+
+import (
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestHTTPFetcher(t *testing.T) {
+	var attempts int
+	mux := http.NewServeMux()
+	mux.HandleFunc("/page", func(w http.ResponseWriter, req *http.Request) {
+		cookie, _ := req.Cookie("session")
+		if req.UserAgent() != DefaultUserAgent || req.Header.Get("X-Token") != "abc" || cookie == nil || cookie.Value != "1" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		w.Write([]byte("<p>hello</p>"))
+	})
+	mux.HandleFunc("/flaky", func(w http.ResponseWriter, req *http.Request) {
+		if attempts++; attempts < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.Write([]byte("<p>ok</p>"))
+	})
+	mux.HandleFunc("/down", func(w http.ResponseWriter, req *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	})
+	mux.HandleFunc("/missing", func(w http.ResponseWriter, req *http.Request) {
+		http.NotFound(w, req)
+	})
+	mux.HandleFunc("/loop", func(w http.ResponseWriter, req *http.Request) {
+		http.Redirect(w, req, "/loop", http.StatusFound)
+	})
+	mux.HandleFunc("/large", func(w http.ResponseWriter, req *http.Request) {
+		w.Write([]byte(strings.Repeat("a", 1024)))
+	})
+	server := httptest.NewTLSServer(mux)
+	defer server.Close()
+
+	// 默认禁止访问回环地址
+	var policyErr *PolicyError
+	if _, err := NewDefault().ParseByURL(server.URL+"/page", ""); !errors.As(err, &policyErr) || policyErr.Code != PolicyPrivateIP {
+		t.Errorf("unexpected error of net policy: %v", err)
+	}
+	// 默认校验证书
+	loopback := &NetPolicy{AllowCIDRs: []string{"127.0.0.0/8"}}
+	f := NewFetcher()
+	f.Policy = loopback
+	if _, err := NewDefault().SetFetcher(f).ParseByURL(server.URL+"/page", ""); err == nil || errors.As(err, &policyErr) {
+		t.Errorf("expect certificate error: %v", err)
+	}
+
+	f = NewFetcher()
+	f.Policy = loopback
+	f.InsecureSkipVerify = true
+	f.Header.Set("X-Token", "abc")
+	f.Cookies = []*http.Cookie{{Name: "session", Value: "1"}}
+	f.RetryBackoff = time.Millisecond
+	f.MaxBodyBytes = 512
+	r := NewDefault().SetClassPrefix("").SetFetcher(f)
+
+	nodes, err := r.ParseByURL(server.URL+"/page", "")
+	if expect := `[{"name":"p","children":[{"type":"text","text":"hello"}]}]`; err != nil || toJSON(nodes) != expect {
+		t.Errorf("unexpected nodes: %v %v", toJSON(nodes), err)
+	}
+	nodes, err = r.ParseByURL(server.URL+"/flaky", "")
+	if err != nil || attempts != 3 || InnerText(nodes) != "ok" {
+		t.Errorf("unexpected retry: %v %v %v", attempts, toJSON(nodes), err)
+	}
+	if _, err = r.ParseByURL(server.URL+"/loop", ""); !errors.Is(err, ErrTooManyRedirects) {
+		t.Errorf("unexpected error of redirects: %v", err)
+	}
+	if _, err = r.ParseByURL(server.URL+"/large", ""); err == nil || !strings.Contains(err.Error(), "body limit") {
+		t.Errorf("unexpected error of body size: %v", err)
+	}
+	// 非 2xx 的响应以及重试之后仍然失败的 5xx 响应返回 StatusError
+	var statusErr *StatusError
+	for path, code := range map[string]int{"/missing": http.StatusNotFound, "/down": http.StatusServiceUnavailable} {
+		if _, err = r.ParseByURL(server.URL+path, ""); !errors.As(err, &statusErr) || statusErr.StatusCode != code {
+			t.Errorf("unexpected error of %v: %v", path, err)
+		}
+	}
+	// MaxRedirects 为 0 时不跟随重定向
+	f.MaxRedirects = 0
+	if _, err = NewDefault().SetFetcher(f).ParseByURL(server.URL+"/loop", ""); !errors.As(err, &statusErr) || statusErr.StatusCode != http.StatusFound {
+		t.Errorf("unexpected error without redirects: %v", err)
+	}
+}
+
+func TestFetcherFunc(t *testing.T) {
+	r := NewDefault().SetClassPrefix("").SetFetcher(FetcherFunc(func(req *http.Request) (*http.Response, error) {
+		return &http.Response{
+			StatusCode: http.StatusOK,
+			Header:     http.Header{"Content-Type": {"text/html"}},
+			Body:       ioutil.NopCloser(strings.NewReader("<p>" + req.URL.Path + "</p>")),
+		}, nil
+	}))
+	nodes, err := r.ParseByURL("http://example.com/fake", "")
+	if err != nil || InnerText(nodes) != "/fake" {
+		t.Errorf("unexpected nodes: %v %v", toJSON(nodes), err)
+	}
+}
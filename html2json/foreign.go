@@ -0,0 +1,99 @@
+package html2json
+
+import (
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// 与 golang.org/x/net/html 一致，用于 Encode 处理 svg、math 等外部内容
+
+// 外部内容中出现这些 HTML 元素时结束外部内容
+var breakoutTags = map[string]bool{
+	"b": true, "big": true, "blockquote": true, "body": true, "br": true, "center": true, "code": true, "dd": true,
+	"div": true, "dl": true, "dt": true, "em": true, "embed": true, "h1": true, "h2": true, "h3": true, "h4": true,
+	"h5": true, "h6": true, "head": true, "hr": true, "i": true, "img": true, "li": true, "listing": true, "menu": true,
+	"meta": true, "nobr": true, "ol": true, "p": true, "pre": true, "ruby": true, "s": true, "small": true, "span": true,
+	"strong": true, "strike": true, "sub": true, "sup": true, "table": true, "tt": true, "u": true, "ul": true, "var": true,
+}
+
+// 词法分析得到的属性名称均为小写，svg 中需要恢复大小写
+var svgAttrNames = map[string]string{
+	"attributename": "attributeName", "attributetype": "attributeType", "basefrequency": "baseFrequency",
+	"baseprofile": "baseProfile", "calcmode": "calcMode", "clippathunits": "clipPathUnits",
+	"contentscripttype": "contentScriptType", "contentstyletype": "contentStyleType", "diffuseconstant": "diffuseConstant",
+	"edgemode": "edgeMode", "externalresourcesrequired": "externalResourcesRequired", "filterres": "filterRes",
+	"filterunits": "filterUnits", "glyphref": "glyphRef", "gradienttransform": "gradientTransform",
+	"gradientunits": "gradientUnits", "kernelmatrix": "kernelMatrix", "kernelunitlength": "kernelUnitLength",
+	"keypoints": "keyPoints", "keysplines": "keySplines", "keytimes": "keyTimes", "lengthadjust": "lengthAdjust",
+	"limitingconeangle": "limitingConeAngle", "markerheight": "markerHeight", "markerunits": "markerUnits",
+	"markerwidth": "markerWidth", "maskcontentunits": "maskContentUnits", "maskunits": "maskUnits",
+	"numoctaves": "numOctaves", "pathlength": "pathLength", "patterncontentunits": "patternContentUnits",
+	"patterntransform": "patternTransform", "patternunits": "patternUnits", "pointsatx": "pointsAtX",
+	"pointsaty": "pointsAtY", "pointsatz": "pointsAtZ", "preservealpha": "preserveAlpha",
+	"preserveaspectratio": "preserveAspectRatio", "primitiveunits": "primitiveUnits", "refx": "refX", "refy": "refY",
+	"repeatcount": "repeatCount", "repeatdur": "repeatDur", "requiredextensions": "requiredExtensions",
+	"requiredfeatures": "requiredFeatures", "specularconstant": "specularConstant", "specularexponent": "specularExponent",
+	"spreadmethod": "spreadMethod", "startoffset": "startOffset", "stddeviation": "stdDeviation",
+	"stitchtiles": "stitchTiles", "surfacescale": "surfaceScale", "systemlanguage": "systemLanguage",
+	"tablevalues": "tableValues", "targetx": "targetX", "targety": "targetY", "textlength": "textLength",
+	"viewbox": "viewBox", "viewtarget": "viewTarget", "xchannelselector": "xChannelSelector",
+	"ychannelselector": "yChannelSelector", "zoomandpan": "zoomAndPan",
+}
+
+var mathAttrNames = map[string]string{
+	"definitionurl": "definitionURL",
+}
+
+// breakout 判断外部内容中的元素是否为 HTML 元素
+func breakout(name string, attrs []html.Attribute) bool {
+	if name == "font" {
+		for _, a := range attrs {
+			if a.Key == "color" || a.Key == "face" || a.Key == "size" {
+				return true
+			}
+		}
+	}
+	return breakoutTags[name]
+}
+
+// adjustForeignAttrs 恢复外部内容中属性名称的大小写，并将 xlink:href 等属性拆分为命名空间和名称
+func adjustForeignAttrs(ns string, attrs []html.Attribute) {
+	names := svgAttrNames
+	if ns == "math" {
+		names = mathAttrNames
+	}
+	for i, a := range attrs {
+		if name, ok := names[a.Key]; ok {
+			attrs[i].Key = name
+			continue
+		}
+		switch a.Key {
+		case "xlink:actuate", "xlink:arcrole", "xlink:href", "xlink:role", "xlink:show",
+			"xlink:title", "xlink:type", "xml:base", "xml:lang", "xml:space", "xmlns:xlink":
+			j := strings.Index(a.Key, ":")
+			attrs[i].Namespace, attrs[i].Key = a.Key[:j], a.Key[j+1:]
+		}
+	}
+}
+
+// integrationPoint 判断外部内容中的元素是否为 HTML 集成点，其中的元素按照 HTML 解析
+func integrationPoint(ns, name string, attrs []html.Attribute) bool {
+	switch ns {
+	case "svg":
+		return name == "foreignobject" || name == "desc" || name == "title"
+	case "math":
+		switch name {
+		case "mi", "mo", "mn", "ms", "mtext":
+			return true
+		case "annotation-xml":
+			for _, a := range attrs {
+				if a.Key == "encoding" {
+					val := strings.ToLower(a.Val)
+					return val == "text/html" || val == "application/xhtml+xml"
+				}
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,174 @@
+package html2json
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/alecthomas/chroma"
+	"github.com/alecthomas/chroma/lexers"
+	"github.com/alecthomas/chroma/styles"
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// DefaultHighlightTheme 代码高亮默认使用的主题
+const DefaultHighlightTheme = "github"
+
+// HighlightOptions 代码高亮的选项
+type HighlightOptions struct {
+	Theme       string // chroma 的主题名称，如 github、monokai、dracula，默认为 DefaultHighlightTheme
+	LineNumbers bool   // 是否在每行代码之前输出行号
+	Label       bool   // 是否在代码块的开头输出语言名称
+}
+
+// SetHighlight 设置代码高亮，opts 为 nil 时不高亮。
+// 根据 pre 或者其中的 code 元素的 language-xxx、lang-xxx class 识别语言，markdown 代码块的语言会被转换为该 class，
+// 高亮之后的代码为带有行内颜色样式的 span 节点，无法识别语言的代码块保持不变
+func (r *RichText) SetHighlight(opts *HighlightOptions) *RichText {
+	r.highlight = opts
+	return r
+}
+
+// HighlightThemes 返回可用的主题名称
+func HighlightThemes() []string {
+	return styles.Names()
+}
+
+// highlight 高亮 pre 中的代码，返回 pre 的子节点以及需要添加到 pre 的样式，无法高亮时返回 nil
+func (c *converter) highlight(pre *html.Node) (children []Node, style string) {
+	// pre 中只能有一个 code 元素，或者直接是代码
+	var code *html.Node
+	for item := pre.FirstChild; item != nil; item = item.NextSibling {
+		switch item.Type {
+		case html.ElementNode:
+			if code != nil || item.DataAtom != atom.Code {
+				return
+			}
+			code = item
+		case html.TextNode:
+			if strings.TrimSpace(item.Data) != "" && pre.FirstChild != pre.LastChild {
+				return
+			}
+		}
+	}
+	lang := ""
+	if code != nil {
+		lang = codeLanguage(code)
+	}
+	if lang == "" {
+		lang = codeLanguage(pre)
+	}
+	lexer := lexers.Get(lang)
+	if lexer == nil {
+		return
+	}
+
+	src := pre
+	if code != nil {
+		src = code
+	}
+	it, err := chroma.Coalesce(lexer).Tokenise(nil, nodeText(src))
+	if err != nil {
+		return
+	}
+
+	opts := c.r.highlight
+	theme := opts.Theme
+	if theme == "" {
+		theme = DefaultHighlightTheme
+	}
+	s := styles.Get(theme)
+	bg := s.Get(chroma.Background)
+
+	var spans []Node
+	if opts.LineNumbers {
+		lines := chroma.SplitTokensIntoLines(it.Tokens())
+		width := len(fmt.Sprint(len(lines)))
+		lineStyle := tokenStyle(s.Get(chroma.LineNumbers), bg) + "margin-right: 1em;"
+		for i, line := range lines {
+			spans = append(spans, Node{Name: "span", Attrs: map[string]string{"style": lineStyle},
+				Children: []Node{{Type: "text", Text: fmt.Sprintf("%*d", width, i+1)}}})
+			spans = appendTokens(spans, line, s, bg)
+		}
+	} else {
+		spans = appendTokens(spans, it.Tokens(), s, bg)
+	}
+
+	if opts.Label {
+		label := tokenStyle(s.Get(chroma.Comment), bg) + "display: block;font-size: 0.8em;margin-bottom: 0.5em;"
+		children = append(children, Node{Name: "span", Attrs: map[string]string{"style": label},
+			Children: []Node{{Type: "text", Text: lexer.Config().Name}}})
+	}
+	if code != nil {
+		h, _, ok := c.element(code)
+		if !ok {
+			return nil, ""
+		}
+		h.Children = spans
+		children = append(children, h)
+	} else {
+		children = append(children, spans...)
+	}
+
+	if bg.Background.IsSet() {
+		style += "background-color: " + bg.Background.String() + ";"
+	}
+	if bg.Colour.IsSet() {
+		style += "color: " + bg.Colour.String() + ";"
+	}
+	return
+}
+
+// appendTokens 将代码转换为 span 节点，没有样式的代码为文本节点
+func appendTokens(nodes []Node, tokens []chroma.Token, s *chroma.Style, bg chroma.StyleEntry) []Node {
+	for _, token := range tokens {
+		if token.Value == "" {
+			continue
+		}
+		text := Node{Type: "text", Text: token.Value}
+		style := tokenStyle(s.Get(token.Type), bg)
+		if style == "" {
+			// 与前一个文本节点合并
+			if l := len(nodes); l > 0 && nodes[l-1].Type == "text" {
+				nodes[l-1].Text += token.Value
+			} else {
+				nodes = append(nodes, text)
+			}
+			continue
+		}
+		nodes = append(nodes, Node{Name: "span", Attrs: map[string]string{"style": style}, Children: []Node{text}})
+	}
+	return nodes
+}
+
+// tokenStyle 返回与背景不同的样式
+func tokenStyle(entry, bg chroma.StyleEntry) (style string) {
+	if entry.Colour.IsSet() && entry.Colour != bg.Colour {
+		style += "color: " + entry.Colour.String() + ";"
+	}
+	if entry.Background.IsSet() && entry.Background != bg.Background {
+		style += "background-color: " + entry.Background.String() + ";"
+	}
+	if entry.Bold == chroma.Yes {
+		style += "font-weight: bold;"
+	}
+	if entry.Italic == chroma.Yes {
+		style += "font-style: italic;"
+	}
+	if entry.Underline == chroma.Yes {
+		style += "text-decoration: underline;"
+	}
+	return
+}
+
+// codeLanguage 根据 language-xxx 或者 lang-xxx class 识别代码的语言
+func codeLanguage(node *html.Node) string {
+	for _, class := range strings.Fields(getAttr(node, "class")) {
+		for _, prefix := range []string{"language-", "lang-"} {
+			if strings.HasPrefix(class, prefix) {
+				return strings.TrimPrefix(class, prefix)
+			}
+		}
+	}
+	return ""
+}
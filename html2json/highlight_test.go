@@ -0,0 +1,101 @@
+package html2json
+
+import (
+	"bytes"
+	"errors"
+	"io/ioutil"
+	"strings"
+	"testing"
+)
+
+func TestRichText_SetHighlight(t *testing.T) {
+	md := "```go\nfunc main() {\n\tfmt.Println(\"hello\")\n}\n```\n"
+	code := "func main() {\n\tfmt.Println(\"hello\")\n}\n"
+
+	r := rt.Clone().SetClassPrefix("").SetHighlight(&HighlightOptions{})
+	nodes, err := r.ParseMarkdown(md, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(nodes) == 0 || len(nodes[0].Children) != 1 {
+		t.Fatalf("unexpected nodes: %v", toJSON(nodes))
+	}
+	pre, codeNode := nodes[0], nodes[0].Children[0]
+	if !strings.HasPrefix(pre.Attrs["style"], preStyle) || !strings.Contains(pre.Attrs["style"], "background-color: #ffffff;") {
+		t.Errorf("unexpected pre style: %v", pre.Attrs["style"])
+	}
+	if codeNode.Name != "code" || codeNode.Attrs["class"] != "language-go" || InnerText(codeNode.Children) != code {
+		t.Errorf("unexpected code: %v", toJSON(codeNode))
+	}
+	var keyword *Node
+	Inspect(codeNode.Children, func(node *Node, path Path) WalkAction {
+		if node.Name == "span" && InnerText(node.Children) == "func" {
+			keyword = node
+		}
+		return WalkContinue
+	})
+	if keyword == nil || !strings.Contains(keyword.Attrs["style"], "font-weight: bold;") {
+		t.Errorf("keyword is not highlighted: %v", toJSON(codeNode))
+	}
+
+	// 行号和语言名称
+	r.SetHighlight(&HighlightOptions{Theme: "monokai", LineNumbers: true, Label: true})
+	nodes, _ = r.Parse(`<pre class="lang-python">print(1)
+print(2)</pre>`, "")
+	children := nodes[0].Children
+	if got := InnerText(children); got != "Python1print(1)\n2print(2)" {
+		t.Errorf("unexpected text: %q", got)
+	}
+	if !strings.Contains(children[0].Attrs["style"], "display: block;") || !strings.Contains(nodes[0].Attrs["style"], "background-color: #272822;") {
+		t.Errorf("unexpected nodes: %v", toJSON(nodes))
+	}
+
+	// 无法识别语言以及未开启高亮时保持不变
+	plain := `[{"name":"div","attrs":{"style":"` + preStyle + `"},"children":[{"name":"code","attrs":{"class":"language-unknown"},"children":[{"type":"text","text":"a \u003c b"}]}]}]`
+	for _, r := range []*RichText{r, rt.Clone().SetClassPrefix("")} {
+		nodes, _ = r.Parse(`<pre><code class="language-unknown">a &lt; b</code></pre>`, "")
+		if got := toJSON(nodes); got != plain {
+			t.Errorf("unexpected nodes:\n%v\n%v", got, plain)
+		}
+	}
+}
+
+func TestRichText_Encode_highlight(t *testing.T) {
+	r := rt.Clone().SetClassPrefix("").SetHighlight(&HighlightOptions{Label: true})
+	cases := []string{
+		`<pre class="language-go">func main() {}</pre>`,
+		"<pre>\n<code class=\"language-go\">func main() {\n\tfmt.Println(\"a &lt; b\")\n}</code></pre><p>after</p>",
+		`<pre class="language-unknown"><b>x</b> y</pre>`,
+	}
+	for _, htmlStr := range cases {
+		nodes, err := r.Parse(htmlStr, "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		var buf bytes.Buffer
+		if err = r.Encode(&buf, strings.NewReader(htmlStr), EncodeOptions{}); err != nil {
+			t.Fatal(err)
+		}
+		if buf.String() != toJSON(nodes) {
+			t.Errorf("unexpected encoded nodes:\n%v\n%v", buf.String(), toJSON(nodes))
+		}
+		if !strings.Contains(InnerText(nodes), "main") && !strings.Contains(InnerText(nodes), "x y") {
+			t.Errorf("code is dropped: %v", toJSON(nodes))
+		}
+	}
+
+	// 高亮生成的节点同样受到限制
+	htmlStr := "<pre class=\"language-go\">" + strings.Repeat("a := 1\n", 100) + "</pre>"
+	r.SetLimits(Limits{MaxNodes: 50})
+	if _, err := r.Parse(htmlStr, ""); !isLimitError(err, "nodes") {
+		t.Errorf("expect nodes limit error, got %v", err)
+	}
+	if err := r.Encode(ioutil.Discard, strings.NewReader(htmlStr), EncodeOptions{}); !isLimitError(err, "nodes") {
+		t.Errorf("expect nodes limit error of encode, got %v", err)
+	}
+}
+
+func isLimitError(err error, limit string) bool {
+	var e *LimitError
+	return errors.As(err, &e) && e.Limit == limit
+}
@@ -2,40 +2,72 @@ package html2json
 
 import (
 	"bytes"
-	"crypto/tls"
+	"context"
 	"fmt"
-	"io/ioutil"
-	"net/http"
+	"io"
 	"net/url"
 	"path"
+	"sort"
 	"strings"
 	"sync"
 	"time"
 
-	"github.com/astaxie/beego/httplib"
 	"github.com/russross/blackfriday"
 
 	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
 
 	"github.com/PuerkitoBio/goquery"
 )
 
-// html to json struct
-type h2j struct {
+const (
+	DefaultClassPrefix = "tag-"
+	preStyle           = "display: block;font-family: monospace;white-space: pre;margin: 1em 0;" // set default <pre> css
+)
+
+// Node 为 HTML 转 JSON 之后的节点
+type Node struct {
 	Name     string            `json:"name,omitempty"` // 对应 HTML 标签
 	Type     string            `json:"type,omitempty"` // element 或者 text
 	Text     string            `json:"text,omitempty"`
 	Attrs    map[string]string `json:"attrs,omitempty"`
-	Children []h2j             `json:"children,omitempty"`
+	Children []Node            `json:"children,omitempty"`
 }
 
-type inode struct {
-	Type string `json:"type"`
-	Data []h2j  `json:"data"`
-}
+// ClassFunc 根据元素原始的标签名称 tag 以及转换后的标签名称 name 生成 class，返回空字符串表示不添加
+type ClassFunc func(tag, name string) string
 
 type RichText struct {
 	tagsMap sync.Map
+	options
+}
+
+// options RichText 中除信任标签之外的配置，Clone 时直接复制
+type options struct {
+	attrs     map[string][]string // 标签允许的属性，为 nil 时不做限制
+	sanitizer *Sanitizer
+
+	classPrefix string
+	classFunc   ClassFunc
+	transforms  map[string][]Transform
+	limits      Limits
+	engine      Engine
+	whitespace  Whitespace
+
+	inlineStyles bool // 将 <style> 中的样式合并到元素的 style 属性中
+	highlight    *HighlightOptions
+	headingIDs   bool     // 为标题生成 id
+	lazyAttrs    []string // 图片懒加载的属性
+	imageWidth   int      // 选择响应式图片时的目标宽度
+	imageDensity float64  // 选择响应式图片时的目标像素密度
+	charset      string   // 指定的字符集，为空时自动检测
+	fetcher      Fetcher
+	linkOptions  LinkOptions
+	cache        Cache
+	cacheTTL     time.Duration
+
+	selectors []string // 需要转换的元素，为空时转换 body 的全部内容
+	excludes  []string // 不需要转换的元素
 }
 
 func NewDefault() *RichText {
@@ -46,294 +78,438 @@ func New(customTags []string) *RichText {
 	if len(customTags) == 0 {
 		customTags = defaultTags
 	}
-	tagsMap := sync.Map{}
+	r := &RichText{options: options{
+		sanitizer:   NewSanitizer(),
+		classPrefix: DefaultClassPrefix,
+		limits:      DefaultLimits,
+		lazyAttrs:   DefaultLazyAttrs,
+
+		imageWidth:   DefaultImageWidth,
+		imageDensity: DefaultImageDensity,
+	}}
 	for _, tag := range customTags {
-		tagsMap.Store(strings.ToLower(tag), true)
+		r.tagsMap.Store(strings.ToLower(tag), true)
+	}
+	return r
+}
+
+// Clone 复制一份配置，可以在不影响原有配置的情况下为单次解析调整选项
+func (r *RichText) Clone() *RichText {
+	c := &RichText{options: r.options}
+	r.tagsMap.Range(func(key, value interface{}) bool {
+		c.tagsMap.Store(key, value)
+		return true
+	})
+	if r.transforms != nil {
+		c.transforms = make(map[string][]Transform, len(r.transforms))
+		for tag, fns := range r.transforms {
+			c.transforms[tag] = append([]Transform{}, fns...)
+		}
+	}
+	return c
+}
+
+// NewWithProfile 根据小程序配置创建 RichText，只输出配置中允许的属性
+func NewWithProfile(p Profile) *RichText {
+	return New(p.Tags).SetAttrs(p.Attrs)
+}
+
+// SetAttrs 设置各标签允许输出的属性，key 为标签名称，"*" 表示对全部标签生效；
+// 属性名称以 * 结尾时按前缀匹配，如 "data-*"。attrs 为 nil 时不限制属性
+func (r *RichText) SetAttrs(attrs map[string][]string) *RichText {
+	if attrs == nil {
+		r.attrs = nil
+		return r
 	}
-	return &RichText{
-		tagsMap: tagsMap,
+	r.attrs = make(map[string][]string, len(attrs))
+	for tag, keys := range attrs {
+		tag = strings.ToLower(tag)
+		for _, key := range keys {
+			r.attrs[tag] = append(r.attrs[tag], strings.ToLower(key))
+		}
 	}
+	return r
 }
 
-func (r *RichText) ParseMarkdown(md, domain string) (data []h2j, err error) {
-	return r.ParseMarkdownByByte([]byte(md), domain)
+// SetClassPrefix 设置自动生成的 class 前缀，默认为 "tag-"，为空时不自动生成 class
+func (r *RichText) SetClassPrefix(prefix string) *RichText {
+	r.classPrefix = prefix
+	return r
 }
 
-func (r *RichText) ParseMarkdownByByte(mdByte []byte, domain string) (data []h2j, err error) {
-	return r.ParseByByte(blackfriday.Run(mdByte), domain)
+// SetClassFunc 自定义元素自动生成的 class，设置后 class 前缀不再生效，fn 为 nil 时恢复使用前缀
+func (r *RichText) SetClassFunc(fn ClassFunc) *RichText {
+	r.classFunc = fn
+	return r
 }
 
-func (r *RichText) Parse(htmlStr string, domain string) (data []h2j, err error) {
-	var doc *goquery.Document
-	doc, err = goquery.NewDocumentFromReader(strings.NewReader(htmlStr))
-	if err != nil {
+func (r *RichText) ParseMarkdown(md, domain string) (data []Node, err error) {
+	return r.ParseMarkdownContext(context.Background(), md, domain)
+}
+
+func (r *RichText) ParseMarkdownContext(ctx context.Context, md, domain string) (data []Node, err error) {
+	return r.ParseMarkdownByByteContext(ctx, []byte(md), domain)
+}
+
+func (r *RichText) ParseMarkdownByByte(mdByte []byte, domain string) (data []Node, err error) {
+	return r.ParseMarkdownByByteContext(context.Background(), mdByte, domain)
+}
+
+func (r *RichText) ParseMarkdownByByteContext(ctx context.Context, mdByte []byte, domain string) (data []Node, err error) {
+	if err = r.limits.checkInput(int64(len(mdByte))); err != nil {
 		return
 	}
-	doc.Find("body").Each(func(i int, selection *goquery.Selection) {
-		data = r.parse(selection, domain)
-	})
+	return r.ParseByByteContext(ctx, blackfriday.Run(mdByte), domain)
+}
+
+func (r *RichText) Parse(htmlStr string, domain string) (data []Node, err error) {
+	return r.ParseContext(context.Background(), htmlStr, domain)
+}
+
+func (r *RichText) ParseContext(ctx context.Context, htmlStr string, domain string) (data []Node, err error) {
+	if err = r.limits.checkInput(int64(len(htmlStr))); err != nil {
+		return
+	}
+	data, _, _, err = r.parseReader(ctx, strings.NewReader(htmlStr), domain)
 	return
 }
 
-func (r *RichText) ParseByByte(htmlByte []byte, domain string) (data []h2j, err error) {
-	var doc *goquery.Document
-	doc, err = goquery.NewDocumentFromReader(bytes.NewReader(htmlByte))
-	if err != nil {
+func (r *RichText) ParseByByte(htmlByte []byte, domain string) (data []Node, err error) {
+	return r.ParseByByteContext(context.Background(), htmlByte, domain)
+}
+
+func (r *RichText) ParseByByteContext(ctx context.Context, htmlByte []byte, domain string) (data []Node, err error) {
+	if err = r.limits.checkInput(int64(len(htmlByte))); err != nil {
 		return
 	}
-	doc.Find("body").Each(func(i int, selection *goquery.Selection) {
-		data = r.parse(selection, domain)
-	})
+	if htmlByte, err = r.decode(htmlByte, ""); err != nil {
+		return
+	}
+	data, _, _, err = r.parseReader(ctx, bytes.NewReader(htmlByte), domain)
 	return
 }
 
-func (r *RichText) ParseByByteV2(htmlByte []byte, domain string) (inodes []inode, err error) {
-	var doc *goquery.Document
-	doc, err = goquery.NewDocumentFromReader(bytes.NewReader(htmlByte))
+// prepare 在转换之前处理整个文档
+func (r *RichText) prepare(root *html.Node) error {
+	if r.inlineStyles {
+		inlineStyles(root)
+	}
+	r.collapsePictures(root)
+	if err := r.scope(root); err != nil {
+		return err
+	}
+	if r.headingIDs {
+		assignHeadingIDs(root)
+	}
+	return nil
+}
+
+// parseReader 解析 HTML 并转换 body 的内容，同时返回解析得到的文档以及被安全过滤移除的属性
+func (r *RichText) parseReader(ctx context.Context, reader io.Reader, domain string) (data []Node, root *html.Node, removed []Removed, err error) {
+	root, err = html.Parse(reader)
 	if err != nil {
 		return
 	}
+	if err = r.prepare(root); err != nil {
+		return
+	}
+	c := r.newConverter(ctx, domain)
+	c.useBase(root)
+	if body := findElement(root, atom.Body); body != nil {
+		data = c.convert(body)
+	}
+	if c.err != nil {
+		return nil, nil, nil, c.err
+	}
+	return data, root, c.removed, nil
+}
 
-	splitMark := "$@$@$@$"
-	mediaTags := map[string]bool{"audio": true, "video": true, "iframe": true, "img": true}
-	blockTags := map[string]bool{"article": true, "aside": true, "base": true, "body": true, "center": true, "figure": true, "nav": true, "title": true, "h1": true, "h2": true, "h3": true, "h4": true, "h5": true, "h6": true, "p": true, "div": true}
-	doc.Find("body").Each(func(i int, selection *goquery.Selection) {
-		for tag, _ := range mediaTags {
-			doc.Find(tag).Each(func(idx int, sel *goquery.Selection) {
-				if tag != "img" {
-					sel.BeforeHtml(splitMark)
-					sel.AfterHtml(splitMark)
-				} else {
-					tagName := "body"
-					if len(sel.Parent().Nodes) > 0 {
-						tagName = strings.ToLower(sel.Parent().Nodes[0].DataAtom.String())
-					}
-					// 父节点为块节点且除了图片之外没有其他内容
-					if _, ok := blockTags[tagName]; ok && strings.TrimSpace(sel.Parent().Text()) == "" {
-						sel.BeforeHtml(splitMark)
-						sel.AfterHtml(splitMark)
-					}
-				}
-			})
-		}
-	})
-
-	ret, _ := doc.Find("body").Html()
-	slice := strings.Split(ret, splitMark)
+func (r *RichText) ParseByByteV2(htmlByte []byte, domain string) ([]Segment, error) {
+	return r.ParseByByteV2Context(context.Background(), htmlByte, domain)
+}
 
-	var data []h2j
-	for _, item := range slice {
-		if strings.TrimSpace(item) != "" {
-			doc2, _ := goquery.NewDocumentFromReader(strings.NewReader(item))
-			data = append(data, r.parseV2(doc2.Find("body"), domain)...)
-		}
+// ParseByByteV2Context 在 audio、video、iframe 以及单独成段的 img 处切分节点树，使媒体可以使用小程序的原生组件渲染，
+// 切分点前后的内容保留原有的外层元素。代码块、表格以及独立显示的公式同样单独作为一个片段
+func (r *RichText) ParseByByteV2Context(ctx context.Context, htmlByte []byte, domain string) ([]Segment, error) {
+	if err := r.limits.checkInput(int64(len(htmlByte))); err != nil {
+		return nil, err
 	}
+	htmlByte, err := r.decode(htmlByte, "")
+	if err != nil {
+		return nil, err
+	}
+	root, err := html.Parse(bytes.NewReader(htmlByte))
+	if err != nil {
+		return nil, err
+	}
+	if err = r.prepare(root); err != nil {
+		return nil, err
+	}
+	c := r.newConverter(ctx, domain)
+	c.useBase(root)
+	c.keepMedia = true
+	var segments []Segment
+	if body := findElement(root, atom.Body); body != nil {
+		segments = c.segment(body)
+	}
+	if c.err != nil {
+		return nil, c.err
+	}
+	return segments, nil
+}
 
-	var (
-		idata []h2j
-		l     = len(data)
-	)
-
-	for idx, item := range data {
-		if _, ok := mediaTags[item.Name]; ok {
-			if len(idata) > 0 {
-				inodes = append(inodes, inode{"richtext", idata})
-			}
-			inodes = append(inodes, inode{item.Name, []h2j{item}})
-			idata = make([]h2j, 0)
-		} else {
-			idata = append(idata, item)
-			if idx == l-1 {
-				inodes = append(inodes, inode{"richtext", idata})
-			}
-		}
+// ParseByURLV2 获取链接的 HTML 内容并按照 ParseByByteV2 的规则解析，timeout 为超时时间(秒)，默认为 10 秒
+func (r *RichText) ParseByURLV2(urlStr string, domain string, timeout ...int) ([]Segment, error) {
+	to := 10 * time.Second
+	if len(timeout) > 0 && timeout[0] > 0 {
+		to = time.Duration(timeout[0]) * time.Second
 	}
+	ctx, cancel := context.WithTimeout(context.Background(), to)
+	defer cancel()
+	return r.ParseByURLV2Context(ctx, urlStr, domain)
+}
 
+func (r *RichText) ParseByURLV2Context(ctx context.Context, urlStr string, domain string) (segments []Segment, err error) {
+	err = r.fetchParse(ctx, urlStr, domain, "segments", &segments, func(b []byte) (interface{}, error) {
+		segments, err = r.ParseByByteV2Context(ctx, b, domain)
+		return segments, err
+	})
 	return
 }
 
-func (r *RichText) ParseByURL(urlStr string, domain string, timeout ...int) (data []h2j, err error) {
-	var (
-		resp *http.Response
-		b    []byte
-	)
-	req := httplib.Get(urlStr)
-	if strings.HasPrefix(strings.ToLower(urlStr), "https://") {
-		req.SetTLSClientConfig(&tls.Config{InsecureSkipVerify: true})
-	}
-	req.Header("user-agent", "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/76.0.3809.87 Safari/537.36")
+// ParseByURL 获取链接的 HTML 内容并解析，timeout 为超时时间(秒)，默认为 10 秒
+func (r *RichText) ParseByURL(urlStr string, domain string, timeout ...int) (data []Node, err error) {
 	to := 10 * time.Second
 	if len(timeout) > 0 && timeout[0] > 0 {
 		to = time.Duration(timeout[0]) * time.Second
 	}
-	resp, err = req.SetTimeout(to, to).Response()
-	if err != nil {
-		return
+	ctx, cancel := context.WithTimeout(context.Background(), to)
+	defer cancel()
+	return r.ParseByURLContext(ctx, urlStr, domain)
+}
+
+// ParseByURLContext 获取链接的 HTML 内容并解析，超时时间由 ctx 控制
+func (r *RichText) ParseByURLContext(ctx context.Context, urlStr string, domain string) (data []Node, err error) {
+	err = r.fetchParse(ctx, urlStr, domain, "nodes", &data, func(b []byte) (interface{}, error) {
+		data, err = r.ParseByByteContext(ctx, b, domain)
+		return data, err
+	})
+	return
+}
+
+// converter 保存单次转换过程中的状态
+type converter struct {
+	r         *RichText
+	ctx       context.Context
+	assetBase string   // 补全静态资源链接的域名
+	linkBase  string   // 补全 a 标签链接的域名
+	baseHref  *url.URL // 文档中的 <base href>
+	keepMedia bool     // 保留不支持的 audio、video、iframe 标签，用于 ParseByByteV2
+	removed   []Removed
+	stream    bool // 用于 Encode，元素的子节点在转换元素本身时还没有解析
+	nodes     int
+	size      int64
+	err       error
+}
+
+func (r *RichText) newConverter(ctx context.Context, domain string) *converter {
+	c := &converter{r: r, ctx: ctx, assetBase: r.linkOptions.AssetBase, linkBase: r.linkOptions.LinkBase}
+	if c.assetBase == "" {
+		c.assetBase = domain
 	}
-	defer resp.Body.Close()
-	b, err = ioutil.ReadAll(resp.Body)
-	if err != nil {
-		return
+	if c.linkBase == "" {
+		c.linkBase = domain
+	}
+	return c
+}
+
+// convert 使用设置的解析引擎转换 parent 的子节点
+func (c *converter) convert(parent *html.Node) []Node {
+	if c.r.whitespace == WhitespaceNormal {
+		normalizeSpace(parent)
+	}
+	if c.r.engine == EngineNative {
+		return c.walk(parent, 1)
 	}
-	return r.ParseByByte(b, domain)
+	return c.parse(goquery.NewDocumentFromNode(parent).Selection, 1)
 }
 
-func (r *RichText) parseV2(sel *goquery.Selection, domain string) (data []h2j) {
+// parse 转换 sel 的子节点，depth 为子节点所在的层级
+func (c *converter) parse(sel *goquery.Selection, depth int) (data []Node) {
+	if c.err != nil {
+		return
+	}
 	nodes := sel.Children().Nodes
 	if len(nodes) == 0 {
 		if txt := sel.Text(); txt != "" {
-			data = []h2j{{Text: txt, Type: "text"}}
+			h := Node{Text: txt, Type: "text"}
+			if c.count(h, depth) {
+				data = []Node{h}
+			}
 		}
 		return
 	}
 	sel.Contents().FilterFunction(func(i int, s *goquery.Selection) bool {
 		ns := s.Nodes
 		for _, item := range ns {
-			var h h2j
+			// 忽略注释
+			if c.err != nil || item.Type == html.CommentNode {
+				continue
+			}
 			if item.Type != html.TextNode {
-				h.Name = strings.ToLower(item.Data)
-
-				// 忽略script
-				if h.Name == "script" || h.Name == "link" {
+				h, tag, ok := c.element(item)
+				if !ok || !c.count(h, depth) || !c.countNodes(h.Children, depth+1) {
 					continue
 				}
-
-				// attrs
-				attr := make(map[string]string)
-				for _, a := range item.Attr {
-					attr[a.Key] = a.Val
-				}
-
-				if class, ok := attr["class"]; ok {
-					attr["class"] = fmt.Sprintf("tag-%v %v", h.Name, class)
-				} else {
-					attr["class"] = "tag-" + h.Name
-				}
-
-				switch h.Name {
-				case "img", "audio", "video", "iframe":
-					if src, ok := attr["src"]; ok {
-						attr["src"] = r.fixSourceLink(domain, src)
-					}
-				case "a":
-					if href, ok := attr["href"]; ok {
-						attr["href"] = r.fixSourceLink(domain, href)
-					}
-				}
-
-				// 小程序不支持的HTML标签，全部转为div标签
-				if _, ok := r.tagsMap.Load(h.Name); !ok {
-					switch h.Name {
-					case "pre":
-						h.Name = "div"
-						defaultStyle := "display: block;font-family: monospace;white-space: pre;margin: 1em 0;" // set default <pre> css
-						if style, ok := attr["style"]; ok {
-							attr["style"] = defaultStyle + style
-						} else {
-							attr["style"] = defaultStyle
-						}
-					case "audio", "video", "iframe":
-						if src, ok := attr["src"]; ok {
-							src = r.fixSourceLink(domain, src)
-						}
-					default:
-						h.Name = "div"
-					}
-				}
-				h.Attrs = attr
 				if len(h.Children) == 0 {
-					h.Children = r.parseV2(goquery.NewDocumentFromNode(item).Selection, domain)
+					h.Children = c.parse(goquery.NewDocumentFromNode(item).Selection, depth+1)
 				}
+				data = append(data, c.r.transform(h, item, tag)...)
 			} else {
-				h.Type = "text"
-				h.Text = goquery.NewDocumentFromNode(item).Selection.Text()
+				h := Node{Type: "text", Text: goquery.NewDocumentFromNode(item).Selection.Text()}
+				if c.count(h, depth) {
+					data = append(data, h)
+				}
 			}
-			data = append(data, h)
 		}
 		return true
 	})
 	return
 }
 
-func (r *RichText) parse(sel *goquery.Selection, domain string) (data []h2j) {
-	nodes := sel.Children().Nodes
-	if len(nodes) == 0 {
-		if txt := sel.Text(); txt != "" {
-			data = []h2j{{Text: txt, Type: "text"}}
-		}
+// element 按照内置规则转换元素本身，不包括子节点，高亮的 pre 除外。tag 为原始的标签名称，ok 为 false 时忽略该元素
+func (c *converter) element(item *html.Node) (h Node, tag string, ok bool) {
+	if h, tag, ok = c.shell(item); !ok {
 		return
 	}
-	sel.Contents().FilterFunction(func(i int, s *goquery.Selection) bool {
-		ns := s.Nodes
-		for _, item := range ns {
-			var h h2j
-			if item.Type != html.TextNode {
-				h.Name = strings.ToLower(item.Data)
+	// 流式输出时 pre 还没有子节点，在元素结束时高亮
+	if tag == "pre" && c.r.highlight != nil && !c.stream {
+		if children, style := c.highlight(item); children != nil {
+			h.Children = children
+			if style != "" {
+				h.Attrs["style"] += style
+			}
+		}
+	}
+	return h, tag, true
+}
 
-				// 忽略script
-				if h.Name == "script" || h.Name == "link" {
-					continue
-				}
+// shell 转换元素本身的标签和属性，不处理子节点，audio、video、iframe 转换为 a 标签时除外
+func (c *converter) shell(item *html.Node) (h Node, tag string, ok bool) {
+	r := c.r
+	h.Name = strings.ToLower(item.Data)
 
-				// attrs
-				attr := make(map[string]string)
-				for _, a := range item.Attr {
-					attr[a.Key] = a.Val
-				}
+	// 忽略script
+	if h.Name == "script" || h.Name == "link" {
+		return
+	}
 
-				if class, ok := attr["class"]; ok {
-					attr["class"] = fmt.Sprintf("tag-%v %v", h.Name, class)
-				} else {
-					attr["class"] = "tag-" + h.Name
-				}
+	// attrs
+	tag = h.Name
+	attr := c.collectAttrs(tag, item)
+	// 生成的标题 id 不受属性白名单的限制
+	if r.headingIDs && headingElements[item.DataAtom] {
+		if id := getAttr(item, "id"); id != "" {
+			attr["id"] = id
+		}
+	}
+	if tag == "img" {
+		r.resolveSrcset(tag, item, attr)
+		if len(r.lazyAttrs) > 0 {
+			r.promoteLazySrc(tag, item, attr)
+		}
+	}
 
-				switch h.Name {
-				case "img", "audio", "video":
-					if src, ok := attr["src"]; ok {
-						attr["src"] = r.fixSourceLink(domain, src)
-					}
-				case "a":
-					if href, ok := attr["href"]; ok {
-						attr["href"] = r.fixSourceLink(domain, href)
-					}
-				}
+	switch h.Name {
+	case "img", "audio", "video", "iframe":
+		if src, ok := attr["src"]; ok && (h.Name != "iframe" || c.keepMedia) {
+			attr["src"] = c.assetLink(src)
+		}
+	case "a":
+		if href, ok := attr["href"]; ok {
+			attr["href"] = c.hrefLink(href)
+		}
+	}
 
-				// 小程序不支持的HTML标签，全部转为div标签
-				if _, ok := r.tagsMap.Load(h.Name); !ok {
-					switch h.Name {
-					case "pre":
-						h.Name = "div"
-						defaultStyle := "display: block;font-family: monospace;white-space: pre;margin: 1em 0;" // set default <pre> css
-						if style, ok := attr["style"]; ok {
-							attr["style"] = defaultStyle + style
-						} else {
-							attr["style"] = defaultStyle
-						}
-					case "audio", "video", "iframe":
-						if src, ok := attr["src"]; ok {
-							src = r.fixSourceLink(domain, src)
-							attr["href"] = src
-							delete(attr, "src")
-							h.Children = []h2j{{Type: "text", Text: fmt.Sprintf(" [%v] %v ", h.Name, src)}}
-						}
-						h.Name = "a"
-					default:
-						h.Name = "div"
-					}
-				}
-				h.Attrs = attr
-				if len(h.Children) == 0 {
-					h.Children = r.parse(goquery.NewDocumentFromNode(item).Selection, domain)
-				}
+	// 小程序不支持的HTML标签，全部转为div标签
+	if _, ok := r.tagsMap.Load(h.Name); !ok {
+		switch h.Name {
+		case "pre":
+			h.Name = "div"
+			if style, ok := attr["style"]; ok {
+				attr["style"] = preStyle + style
 			} else {
-				h.Type = "text"
-				h.Text = goquery.NewDocumentFromNode(item).Selection.Text()
+				attr["style"] = preStyle
+			}
+		case "audio", "video", "iframe":
+			if c.keepMedia {
+				break
+			}
+			if src, ok := attr["src"]; ok {
+				src = c.assetLink(src)
+				attr["href"] = src
+				delete(attr, "src")
+				h.Children = []Node{{Type: "text", Text: fmt.Sprintf(" [%v] %v ", h.Name, src)}}
 			}
-			data = append(data, h)
+			h.Name = "a"
+		default:
+			h.Name = "div"
 		}
+	}
+	r.addClass(attr, tag, h.Name)
+	h.Attrs = attr
+	return h, tag, true
+}
+
+// addClass 在元素原有的 class 之前添加自动生成的 class
+func (r *RichText) addClass(attr map[string]string, tag, name string) {
+	var class string
+	if r.classFunc != nil {
+		class = r.classFunc(tag, name)
+	} else if r.classPrefix != "" {
+		class = r.classPrefix + tag
+	}
+	if class == "" {
+		return
+	}
+	if origin, ok := attr["class"]; ok {
+		attr["class"] = fmt.Sprintf("%v %v", class, origin)
+	} else {
+		attr["class"] = class
+	}
+}
+
+// collectAttrs 获取元素中允许输出并且安全的属性，被安全过滤移除的属性记录在 c.removed 中
+func (c *converter) collectAttrs(tag string, item *html.Node) map[string]string {
+	r := c.r
+	attr := make(map[string]string)
+	for _, a := range item.Attr {
+		if r.allowAttr(tag, a.Key) {
+			attr[a.Key] = a.Val
+		}
+	}
+	if r.sanitizer != nil {
+		removed := r.sanitizer.Sanitize(tag, attr)
+		sort.Slice(removed, func(i, j int) bool { return removed[i].Attr < removed[j].Attr })
+		c.removed = append(c.removed, removed...)
+	}
+	return attr
+}
+
+// allowAttr 判断标签是否允许输出该属性
+func (r *RichText) allowAttr(tag, key string) bool {
+	if r.attrs == nil {
 		return true
-	})
-	return
+	}
+	key = strings.ToLower(key)
+	for _, t := range []string{"*", tag} {
+		for _, allow := range r.attrs[t] {
+			if allow == key || (strings.HasSuffix(allow, "*") && strings.HasPrefix(key, strings.TrimSuffix(allow, "*"))) {
+				return true
+			}
+		}
+	}
+	return false
 }
 
 func (r *RichText) fixSourceLink(domain, link string) string {
@@ -341,6 +517,11 @@ func (r *RichText) fixSourceLink(domain, link string) string {
 		return link
 	}
 
+	// 文档内的链接
+	if strings.HasPrefix(link, "#") {
+		return link
+	}
+
 	link = strings.ReplaceAll(link, "\\", "/")
 
 	if strings.HasPrefix(link, "//") {
@@ -352,6 +533,11 @@ func (r *RichText) fixSourceLink(domain, link string) string {
 		return link
 	}
 
+	// mailto、tel 等其他协议的链接
+	if urlScheme(link) != "" {
+		return link
+	}
+
 	u, err := url.Parse(domain)
 
 	if err != nil {
@@ -48,6 +48,21 @@ func TestParse_Media(t *testing.T) {
 	ioutil.WriteFile("examples/media.json", []byte(toJSON(nodes)), os.ModePerm)
 }
 
+func TestNewWithProfile(t *testing.T) {
+	r := NewWithProfile(Profile{
+		Tags:  []string{"div", "img"},
+		Attrs: map[string][]string{"*": {"class", "aria-*"}, "img": {"src"}},
+	})
+	nodes, err := r.Parse(`<div id="box" aria-label="box" onclick="alert(1)"><img src="a.png" alt="a" data-id="1"></div>`, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expect := `[{"name":"div","attrs":{"aria-label":"box","class":"tag-div"},"children":[{"name":"img","attrs":{"class":"tag-img","src":"a.png"}}]}]`
+	if got := toJSON(nodes); got != expect {
+		t.Errorf("unexpected nodes:\n%v\n%v", got, expect)
+	}
+}
+
 func TestParseByURL(t *testing.T) {
 	nodes, err := rt.ParseByURL("https://my.oschina.net/huanghaibin/blog/3106432", "")
 	if err != nil {
@@ -78,3 +93,30 @@ func BenchmarkParse2(b *testing.B) {
 		rt.Parse("<div>hello world</div>", "")
 	}
 }
+
+func TestRichText_SetClassPrefix(t *testing.T) {
+	htmlStr := `<p class="intro">hello</p><pre>code</pre>`
+	cases := []struct {
+		r      *RichText
+		expect string
+	}{
+		{NewDefault(), `[{"name":"p","attrs":{"class":"tag-p intro"},"children":[{"type":"text","text":"hello"}]},{"name":"div","attrs":{"class":"tag-pre","style":"` + preStyle + `"},"children":[{"type":"text","text":"code"}]}]`},
+		{NewDefault().SetClassPrefix("h2j-"), `[{"name":"p","attrs":{"class":"h2j-p intro"},"children":[{"type":"text","text":"hello"}]},{"name":"div","attrs":{"class":"h2j-pre","style":"` + preStyle + `"},"children":[{"type":"text","text":"code"}]}]`},
+		{NewDefault().SetClassPrefix(""), `[{"name":"p","attrs":{"class":"intro"},"children":[{"type":"text","text":"hello"}]},{"name":"div","attrs":{"style":"` + preStyle + `"},"children":[{"type":"text","text":"code"}]}]`},
+		{NewDefault().SetClassFunc(func(tag, name string) string {
+			if tag != name {
+				return "tag-" + tag
+			}
+			return ""
+		}), `[{"name":"p","attrs":{"class":"intro"},"children":[{"type":"text","text":"hello"}]},{"name":"div","attrs":{"class":"tag-pre","style":"` + preStyle + `"},"children":[{"type":"text","text":"code"}]}]`},
+	}
+	for _, c := range cases {
+		nodes, err := c.r.Parse(htmlStr, "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := toJSON(nodes); got != c.expect {
+			t.Errorf("unexpected nodes:\n%v\n%v", got, c.expect)
+		}
+	}
+}
@@ -0,0 +1,246 @@
+package html2json
+
+import (
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// DefaultLazyAttrs 常见的图片懒加载属性，属性值为图片的真实链接
+var DefaultLazyAttrs = []string{"data-src", "data-original", "data-lazy-src", "data-actualsrc"}
+
+// SetLazyAttrs 设置图片懒加载的属性，默认为 DefaultLazyAttrs，为空时不处理懒加载。
+// img 存在这些属性时，按照顺序使用第一个不为空的属性值替换 src(通常为占位图片)，并移除这些属性
+func (r *RichText) SetLazyAttrs(attrs ...string) *RichText {
+	r.lazyAttrs = make([]string, 0, len(attrs))
+	for _, attr := range attrs {
+		if attr = strings.ToLower(strings.TrimSpace(attr)); attr != "" {
+			r.lazyAttrs = append(r.lazyAttrs, attr)
+		}
+	}
+	return r
+}
+
+// promoteLazySrc 使用懒加载属性中的真实链接替换 img 的 src
+func (r *RichText) promoteLazySrc(tag string, item *html.Node, attr map[string]string) {
+	var src string
+	for _, key := range r.lazyAttrs {
+		if val, ok := findAttr(item, key); ok && strings.TrimSpace(val) != "" {
+			src = strings.TrimSpace(val)
+			break
+		}
+	}
+	for key := range attr {
+		for _, lazy := range r.lazyAttrs {
+			if strings.EqualFold(key, lazy) {
+				delete(attr, key)
+			}
+		}
+	}
+	if src == "" || !r.allowAttr(tag, "src") || r.sanitizer != nil && !r.sanitizer.AllowURL(tag, src) {
+		return
+	}
+	attr["src"] = src
+}
+
+// 选择响应式图片时默认的目标宽度(CSS 像素)和像素密度，即大多数手机的屏幕
+const (
+	DefaultImageWidth   = 375
+	DefaultImageDensity = 2.0
+)
+
+// SetImageTarget 设置从 srcset 以及 <picture> 中选择图片时的目标宽度(CSS 像素)和像素密度，
+// width 为 0 时选择宽度最大的图片，density 不大于 0 时为 1
+func (r *RichText) SetImageTarget(width int, density float64) *RichText {
+	if density <= 0 {
+		density = 1
+	}
+	r.imageWidth, r.imageDensity = width, density
+	return r
+}
+
+// imageCandidate srcset 中的一个候选图片
+type imageCandidate struct {
+	url     string
+	width   int     // w 描述符
+	density float64 // x 描述符，没有描述符时为 1
+}
+
+// parseSrcset 解析 srcset 属性
+func parseSrcset(srcset string) (candidates []imageCandidate) {
+	for s := srcset; ; {
+		s = strings.TrimLeft(s, " \t\n\r\f,")
+		if s == "" {
+			return
+		}
+		end := strings.IndexAny(s, " \t\n\r\f")
+		if end < 0 {
+			end = len(s)
+		}
+		c := imageCandidate{url: s[:end], density: 1}
+		s = s[end:]
+		var descriptors string
+		if strings.HasSuffix(c.url, ",") {
+			c.url = strings.TrimRight(c.url, ",")
+		} else if i := indexOutside(s, ","); i >= 0 {
+			descriptors, s = s[:i], s[i+1:]
+		} else {
+			descriptors, s = s, ""
+		}
+		for _, d := range strings.Fields(descriptors) {
+			n, err := strconv.ParseFloat(d[:len(d)-1], 64)
+			if err != nil || n <= 0 {
+				continue
+			}
+			switch d[len(d)-1] {
+			case 'w':
+				c.width = int(n)
+			case 'x':
+				c.density = n
+			}
+		}
+		candidates = append(candidates, c)
+	}
+}
+
+// pickSrcset 按照目标宽度和像素密度从 srcset 中选择图片：
+// 选择满足像素密度的最小的图片，都不满足时选择最大的图片
+func (r *RichText) pickSrcset(srcset string) string {
+	candidates := parseSrcset(srcset)
+	if len(candidates) == 0 {
+		return ""
+	}
+	density := func(c imageCandidate) float64 {
+		if c.width == 0 {
+			return c.density
+		}
+		if r.imageWidth <= 0 {
+			return math.MaxFloat64
+		}
+		return float64(c.width) / float64(r.imageWidth)
+	}
+	var best, largest *imageCandidate
+	for i := range candidates {
+		c := &candidates[i]
+		d := density(*c)
+		if largest == nil || d > density(*largest) || d == density(*largest) && c.width > largest.width {
+			largest = c
+		}
+		if d >= r.imageDensity && (best == nil || d < density(*best)) {
+			best = c
+		}
+	}
+	if best == nil || r.imageWidth <= 0 && best.width > 0 {
+		best = largest
+	}
+	return best.url
+}
+
+// resolveSrcset 使用 srcset 中选择的图片替换 img 的 src，并移除 srcset 和 sizes
+func (r *RichText) resolveSrcset(tag string, item *html.Node, attr map[string]string) {
+	delete(attr, "srcset")
+	delete(attr, "sizes")
+	srcset, ok := findAttr(item, "srcset")
+	if !ok {
+		return
+	}
+	if src := r.pickSrcset(srcset); src != "" && r.allowAttr(tag, "src") && (r.sanitizer == nil || r.sanitizer.AllowURL(tag, src)) {
+		attr["src"] = src
+	}
+}
+
+var (
+	mediaRegexp = regexp.MustCompile(`(?i)\(\s*(min|max)-width\s*:\s*(\d+(?:\.\d+)?)\s*(px|em|rem)?\s*\)`)
+	// 小程序普遍支持的图片格式
+	imageTypes = map[string]bool{
+		"image/jpeg": true, "image/jpg": true, "image/png": true, "image/gif": true, "image/webp": true, "image/svg+xml": true, "image/bmp": true,
+	}
+)
+
+// mediaMatches 判断 <source> 的 media 是否匹配目标宽度，只支持 min-width 和 max-width
+func (r *RichText) mediaMatches(media string) bool {
+	media = strings.TrimSpace(strings.ToLower(media))
+	if media == "" {
+		return true
+	}
+	for _, query := range strings.Split(media, ",") {
+		query = strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(query), "only"))
+		if query == "all" || query == "screen" {
+			return true
+		}
+		rest := mediaRegexp.ReplaceAllString(query, "")
+		rest = strings.NewReplacer("and", "", "screen", "", "all", "").Replace(rest)
+		if strings.TrimSpace(rest) != "" || r.imageWidth <= 0 {
+			continue
+		}
+		ok := true
+		for _, m := range mediaRegexp.FindAllStringSubmatch(query, -1) {
+			n, _ := strconv.ParseFloat(m[2], 64)
+			if m[3] == "em" || m[3] == "rem" {
+				n *= 16
+			}
+			if m[1] == "min" && float64(r.imageWidth) < n || m[1] == "max" && float64(r.imageWidth) > n {
+				ok = false
+			}
+		}
+		if ok {
+			return true
+		}
+	}
+	return false
+}
+
+// collapsePictures 将 <picture> 替换为其中的 img，src 为第一个匹配的 <source> 中选择的图片
+func (r *RichText) collapsePictures(root *html.Node) {
+	var pictures []*html.Node
+	eachElement(root, func(node *html.Node) bool {
+		if node.DataAtom == atom.Picture {
+			pictures = append(pictures, node)
+		}
+		return true
+	})
+	for _, picture := range pictures {
+		if picture.Parent == nil {
+			continue
+		}
+		img := findElement(picture, atom.Img)
+		if img == nil {
+			continue
+		}
+		for source := picture.FirstChild; source != nil; source = source.NextSibling {
+			if source.Type != html.ElementNode || source.DataAtom != atom.Source {
+				continue
+			}
+			if t := strings.ToLower(strings.TrimSpace(getAttr(source, "type"))); t != "" && !imageTypes[t] || !r.mediaMatches(getAttr(source, "media")) {
+				continue
+			}
+			src := r.pickSrcset(getAttr(source, "srcset"))
+			if src == "" {
+				src = strings.TrimSpace(getAttr(source, "src"))
+			}
+			if src == "" {
+				continue
+			}
+			setAttr(img, "src", src)
+			removeAttr(img, "srcset")
+			removeAttr(img, "sizes")
+			break
+		}
+		img.Parent.RemoveChild(img)
+		picture.Parent.InsertBefore(img, picture)
+		picture.Parent.RemoveChild(picture)
+	}
+}
+
+func removeAttr(node *html.Node, key string) {
+	for i, a := range node.Attr {
+		if a.Namespace == "" && a.Key == key {
+			node.Attr = append(node.Attr[:i], node.Attr[i+1:]...)
+			return
+		}
+	}
+}
@@ -0,0 +1,87 @@
+package html2json
+
+import "testing"
+
+func TestRichText_SetLazyAttrs(t *testing.T) {
+	htmlStr := `<img src="data:image/gif;base64,R0lGODlhAQABAAAAACw=" data-src="/a.png" data-original="/b.png" alt="a">` +
+		`<img src="loading.gif" data-actualsrc="//cdn.bookstack.cn/c.png">` +
+		`<img data-lazy-src="javascript:alert(1)" src="d.png">` +
+		`<img src="e.png" data-url="f.png">`
+	cases := []struct {
+		r      *RichText
+		expect string
+	}{
+		{rt.Clone().SetClassPrefix(""),
+			`[{"name":"img","attrs":{"alt":"a","src":"https://www.bookstack.cn/a.png"}},{"name":"img","attrs":{"src":"http://cdn.bookstack.cn/c.png"}},` +
+				`{"name":"img","attrs":{"src":"https://www.bookstack.cn/static/d.png"}},{"name":"img","attrs":{"data-url":"f.png","src":"https://www.bookstack.cn/static/e.png"}}]`},
+		{rt.Clone().SetClassPrefix("").SetLazyAttrs(append(DefaultLazyAttrs, "DATA-URL")...),
+			`[{"name":"img","attrs":{"alt":"a","src":"https://www.bookstack.cn/a.png"}},{"name":"img","attrs":{"src":"http://cdn.bookstack.cn/c.png"}},` +
+				`{"name":"img","attrs":{"src":"https://www.bookstack.cn/static/d.png"}},{"name":"img","attrs":{"src":"https://www.bookstack.cn/static/f.png"}}]`},
+		{rt.Clone().SetClassPrefix("").SetLazyAttrs(),
+			`[{"name":"img","attrs":{"alt":"a","data-original":"/b.png","data-src":"/a.png","src":"data:image/gif;base64,R0lGODlhAQABAAAAACw="}},{"name":"img","attrs":{"data-actualsrc":"//cdn.bookstack.cn/c.png","src":"https://www.bookstack.cn/static/loading.gif"}},` +
+				`{"name":"img","attrs":{"data-lazy-src":"javascript:alert(1)","src":"https://www.bookstack.cn/static/d.png"}},{"name":"img","attrs":{"data-url":"f.png","src":"https://www.bookstack.cn/static/e.png"}}]`},
+	}
+	for _, c := range cases {
+		nodes, err := c.r.Parse(htmlStr, "https://www.bookstack.cn/static/")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := toJSON(nodes); got != c.expect {
+			t.Errorf("unexpected nodes:\n%v\n%v", got, c.expect)
+		}
+	}
+}
+
+func TestRichText_pickSrcset(t *testing.T) {
+	srcset := "a-320.jpg 320w, a-640.jpg 640w,a-1280.jpg 1280w"
+	cases := []struct {
+		width   int
+		density float64
+		srcset  string
+		expect  string
+	}{
+		{375, 2, srcset, "a-1280.jpg"},
+		{320, 1, srcset, "a-320.jpg"},
+		{320, 2, srcset, "a-640.jpg"},
+		{0, 1, srcset, "a-1280.jpg"},
+		{375, 3, srcset, "a-1280.jpg"},
+		{375, 2, "a.jpg, a@2x.jpg 2x, a@3x.jpg 3x", "a@2x.jpg"},
+		{375, 1.5, "a.jpg 1x,a@3x.jpg 3x", "a@3x.jpg"},
+		{375, 1, "data:image/png;base64,iVBOR, b.png 2x", "data:image/png;base64,iVBOR"},
+		{375, 1, "", ""},
+	}
+	for _, c := range cases {
+		if got := rt.Clone().SetImageTarget(c.width, c.density).pickSrcset(c.srcset); got != c.expect {
+			t.Errorf("unexpected image for %v %v %q: %v, expect %v", c.width, c.density, c.srcset, got, c.expect)
+		}
+	}
+}
+
+func TestRichText_SetImageTarget(t *testing.T) {
+	htmlStr := `<p><picture>` +
+		`<source type="image/avif" srcset="a.avif">` +
+		`<source media="(min-width: 800px)" srcset="wide-1x.webp 1x, wide-2x.webp 2x">` +
+		`<source type="image/webp" srcset="narrow-1x.webp, narrow-2x.webp 2x">` +
+		`<img src="fallback.jpg" srcset="fallback-2x.jpg 2x" alt="a"></picture></p>` +
+		`<img src="b.jpg" srcset="b-480.jpg 480w, b-960.jpg 960w" sizes="100vw">`
+	cases := []struct {
+		r      *RichText
+		expect string
+	}{
+		{rt.Clone().SetClassPrefix(""),
+			`[{"name":"p","children":[{"name":"img","attrs":{"alt":"a","src":"https://www.bookstack.cn/static/narrow-2x.webp"}}]},{"name":"img","attrs":{"src":"https://www.bookstack.cn/static/b-960.jpg"}}]`},
+		{rt.Clone().SetClassPrefix("").SetImageTarget(1024, 1),
+			`[{"name":"p","children":[{"name":"img","attrs":{"alt":"a","src":"https://www.bookstack.cn/static/wide-1x.webp"}}]},{"name":"img","attrs":{"src":"https://www.bookstack.cn/static/b-960.jpg"}}]`},
+		{rt.Clone().SetClassPrefix("").SetImageTarget(240, 1).SetEngine(EngineNative),
+			`[{"name":"p","children":[{"name":"img","attrs":{"alt":"a","src":"https://www.bookstack.cn/static/narrow-1x.webp"}}]},{"name":"img","attrs":{"src":"https://www.bookstack.cn/static/b-480.jpg"}}]`},
+	}
+	for _, c := range cases {
+		nodes, err := c.r.Parse(htmlStr, "https://www.bookstack.cn/static/")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := toJSON(nodes); got != c.expect {
+			t.Errorf("unexpected nodes:\n%v\n%v", got, c.expect)
+		}
+	}
+}
@@ -0,0 +1,129 @@
+package html2json
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+)
+
+// Limits 解析时的资源限制，值为 0 时表示不限制
+type Limits struct {
+	MaxDepth       int   // 节点最大嵌套层级
+	MaxNodes       int   // 最大节点数量，包括元素节点和文本节点
+	MaxInputBytes  int64 // 输入的 HTML 或 markdown 的最大字节数
+	MaxOutputBytes int64 // 输出 JSON 的最大字节数，为估算值
+}
+
+// DefaultLimits 默认的资源限制
+var DefaultLimits = Limits{
+	MaxDepth:       256,
+	MaxNodes:       500000,
+	MaxInputBytes:  32 << 20,
+	MaxOutputBytes: 64 << 20,
+}
+
+// LimitError 超出资源限制时返回的错误
+type LimitError struct {
+	Limit string // depth、nodes、input、output，或者 HTTPFetcher 的 body
+	Max   int64
+}
+
+func (e *LimitError) Error() string {
+	return fmt.Sprintf("html2json: %v limit exceeded, max %v", e.Limit, e.Max)
+}
+
+// CanceledError context 被取消或者超时时返回的错误，可通过 errors.Is 判断是 context.Canceled 还是 context.DeadlineExceeded
+type CanceledError struct {
+	Err error
+}
+
+func (e *CanceledError) Error() string {
+	return fmt.Sprintf("html2json: %v", e.Err)
+}
+
+func (e *CanceledError) Unwrap() error {
+	return e.Err
+}
+
+// canceled ctx 已被取消时返回 *CanceledError，否则返回 err
+func canceled(ctx context.Context, err error) error {
+	if ctx.Err() != nil {
+		return &CanceledError{Err: ctx.Err()}
+	}
+	return err
+}
+
+// SetLimits 设置解析时的资源限制
+func (r *RichText) SetLimits(limits Limits) *RichText {
+	r.limits = limits
+	return r
+}
+
+func (l Limits) checkInput(size int64) error {
+	if l.MaxInputBytes > 0 && size > l.MaxInputBytes {
+		return &LimitError{Limit: "input", Max: l.MaxInputBytes}
+	}
+	return nil
+}
+
+// readAll 读取全部内容，超出 MaxInputBytes 时返回错误
+func (l Limits) readAll(reader io.Reader) ([]byte, error) {
+	if l.MaxInputBytes <= 0 {
+		return ioutil.ReadAll(reader)
+	}
+	b, err := ioutil.ReadAll(io.LimitReader(reader, l.MaxInputBytes+1))
+	if err != nil {
+		return nil, err
+	}
+	if err = l.checkInput(int64(len(b))); err != nil {
+		return nil, err
+	}
+	return b, nil
+}
+
+// 每转换多少个节点检查一次 context 是否已取消
+const checkContextEvery = 128
+
+// count 统计转换的节点，超出限制或者 context 被取消时记录错误并返回 false
+func (c *converter) count(node Node, depth int) bool {
+	if c.err != nil {
+		return false
+	}
+	limits := c.r.limits
+	c.nodes++
+	c.size += estimateSize(node)
+	switch {
+	case limits.MaxDepth > 0 && depth > limits.MaxDepth:
+		c.err = &LimitError{Limit: "depth", Max: int64(limits.MaxDepth)}
+	case limits.MaxNodes > 0 && c.nodes > limits.MaxNodes:
+		c.err = &LimitError{Limit: "nodes", Max: int64(limits.MaxNodes)}
+	case limits.MaxOutputBytes > 0 && c.size > limits.MaxOutputBytes:
+		c.err = &LimitError{Limit: "output", Max: limits.MaxOutputBytes}
+	case c.ctx != nil && c.nodes%checkContextEvery == 0:
+		c.err = canceled(c.ctx, nil)
+	}
+	return c.err == nil
+}
+
+// countNodes 统计转换元素时直接生成的子节点，如高亮之后的代码
+func (c *converter) countNodes(nodes []Node, depth int) bool {
+	for _, node := range nodes {
+		if !c.count(node, depth) || !c.countNodes(node.Children, depth+1) {
+			return false
+		}
+	}
+	return c.err == nil
+}
+
+// estimateSize 估算节点本身(不包括子节点)序列化为 JSON 之后的字节数
+func estimateSize(node Node) int64 {
+	if node.Type == "text" {
+		return int64(len(`{"type":"text","text":""},`) + len(node.Text))
+	}
+	size := len(`{"name":"","attrs":{},"children":[]},`) + len(node.Name)
+	for key, val := range node.Attrs {
+		size += len(`"":"",`) + len(key) + len(val)
+	}
+	return int64(size)
+}
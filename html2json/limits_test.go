@@ -0,0 +1,39 @@
+package html2json
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestRichText_SetLimits(t *testing.T) {
+	htmlStr := strings.Repeat("<div>", 20) + "hello" + strings.Repeat("</div>", 20)
+	cases := []struct {
+		limits Limits
+		limit  string
+	}{
+		{Limits{MaxDepth: 10}, "depth"},
+		{Limits{MaxNodes: 10}, "nodes"},
+		{Limits{MaxInputBytes: 100}, "input"},
+		{Limits{MaxOutputBytes: 100}, "output"},
+	}
+	for _, c := range cases {
+		_, err := NewDefault().SetLimits(c.limits).Parse(htmlStr, "")
+		var e *LimitError
+		if !errors.As(err, &e) || e.Limit != c.limit {
+			t.Errorf("expect %v limit error, got %v", c.limit, err)
+		}
+	}
+
+	if _, err := NewDefault().SetLimits(Limits{}).Parse(htmlStr, ""); err != nil {
+		t.Error(err)
+	}
+
+	var canceledErr *CanceledError
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := rt.ParseContext(ctx, strings.Repeat("<p>hello</p>", 1000), ""); !errors.As(err, &canceledErr) || !errors.Is(err, context.Canceled) {
+		t.Errorf("expect context canceled, got %v", err)
+	}
+}
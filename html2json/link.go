@@ -0,0 +1,116 @@
+package html2json
+
+import (
+	"net/url"
+	"strconv"
+	"strings"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// LinkOptions 补全相对链接的选项，为空的字段使用 Parse 等方法的 domain 参数
+type LinkOptions struct {
+	AssetBase     string // img、audio、video 等静态资源的 src 以及 poster 使用的域名，如 CDN 的域名
+	LinkBase      string // a 标签的 href 使用的域名
+	IgnoreBaseTag bool   // 忽略文档中的 <base href>
+}
+
+// SetLinkOptions 设置补全相对链接的选项。文档中有 <base href> 时，相对链接按照浏览器的规则相对于该地址补全，
+// <base href> 本身为相对链接时相对于 AssetBase、LinkBase 或者 domain 补全
+func (r *RichText) SetLinkOptions(opts LinkOptions) *RichText {
+	r.linkOptions = opts
+	return r
+}
+
+// useBase 使用文档中第一个带有 href 的 <base> 元素
+func (c *converter) useBase(root *html.Node) {
+	if base := findBase(root); base != nil {
+		c.setBase(getAttr(base, "href"))
+	}
+}
+
+func findBase(node *html.Node) *html.Node {
+	if node.Type == html.ElementNode && node.DataAtom == atom.Base && hasAttr(node, "href") {
+		return node
+	}
+	for item := node.FirstChild; item != nil; item = item.NextSibling {
+		if found := findBase(item); found != nil {
+			return found
+		}
+	}
+	return nil
+}
+
+func (c *converter) setBase(href string) {
+	if c.r.linkOptions.IgnoreBaseTag || c.baseHref != nil {
+		return
+	}
+	u, err := url.Parse(strings.TrimSpace(strings.ReplaceAll(href, "\\", "/")))
+	if err != nil || u.Scheme != "" && u.Scheme != "http" && u.Scheme != "https" {
+		return
+	}
+	c.baseHref = u
+}
+
+// assetLink 补全静态资源的链接
+func (c *converter) assetLink(link string) string {
+	return c.resolveLink(c.assetBase, link)
+}
+
+// hrefLink 补全 a 标签的链接
+func (c *converter) hrefLink(link string) string {
+	return c.resolveLink(c.linkBase, link)
+}
+
+// resolveLink 文档中有 <base href> 时按照浏览器的规则补全，否则按照 fixSourceLink 的规则相对于 base 补全
+func (c *converter) resolveLink(base, link string) string {
+	if c.baseHref == nil || strings.HasPrefix(link, "#") || urlScheme(link) != "" {
+		return c.r.fixSourceLink(base, link)
+	}
+	baseURL := c.baseHref
+	if !baseURL.IsAbs() {
+		u, err := url.Parse(base)
+		if base == "" || err != nil {
+			return c.r.fixSourceLink(base, link)
+		}
+		// 与 fixSourceLink 一致，base 的路径作为目录
+		if !strings.HasSuffix(u.Path, "/") {
+			u.Path += "/"
+		}
+		baseURL = u.ResolveReference(baseURL)
+	}
+	ref, err := url.Parse(strings.TrimSpace(strings.ReplaceAll(link, "\\", "/")))
+	if err != nil {
+		return link
+	}
+	u := baseURL.ResolveReference(ref)
+	// 不使用 u.String()，避免对中文进行编码；u.Path 是解码之后的路径，使用 EscapedPath 保留 %20、%3F 等编码
+	link = u.Scheme + "://" + u.Host + unescapeNonASCII(u.EscapedPath())
+	if u.RawQuery != "" {
+		link += "?" + u.RawQuery
+	}
+	if u.Fragment != "" {
+		link += "#" + u.Fragment
+	}
+	return link
+}
+
+// unescapeNonASCII 还原路径中编码的非 ASCII 字符，其他编码保持不变
+func unescapeNonASCII(s string) string {
+	if !strings.Contains(s, "%") {
+		return s
+	}
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] == '%' && i+2 < len(s) {
+			if v, err := strconv.ParseUint(s[i+1:i+3], 16, 8); err == nil && v >= 0x80 {
+				b.WriteByte(byte(v))
+				i += 2
+				continue
+			}
+		}
+		b.WriteByte(s[i])
+	}
+	return b.String()
+}
@@ -0,0 +1,76 @@
+package html2json
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestRichText_SetLinkOptions(t *testing.T) {
+	body := `<img src="/a.png"><video src="v.mp4"></video><a href="page.html">p</a><a href="#top">t</a><a href="mailto:a@b.c">m</a>`
+	cases := []struct {
+		opts   LinkOptions
+		head   string
+		domain string
+		expect []string
+	}{
+		{LinkOptions{}, "", "https://www.bookstack.cn/docs/", []string{
+			"https://www.bookstack.cn/a.png", "https://www.bookstack.cn/docs/v.mp4", "https://www.bookstack.cn/docs/page.html", "#top", "mailto:a@b.c"}},
+		{LinkOptions{AssetBase: "https://cdn.bookstack.cn/static"}, "", "https://www.bookstack.cn/docs/", []string{
+			"https://cdn.bookstack.cn/a.png", "https://cdn.bookstack.cn/static/v.mp4", "https://www.bookstack.cn/docs/page.html", "#top", "mailto:a@b.c"}},
+		{LinkOptions{AssetBase: "https://cdn.bookstack.cn/", LinkBase: "https://m.bookstack.cn/"}, "", "", []string{
+			"https://cdn.bookstack.cn/a.png", "https://cdn.bookstack.cn/v.mp4", "https://m.bookstack.cn/page.html", "#top", "mailto:a@b.c"}},
+		// <base href> 优先于 domain，按照浏览器的规则补全
+		{LinkOptions{}, `<base href="https://example.com/book/chapter.html">`, "https://www.bookstack.cn/", []string{
+			"https://example.com/a.png", "https://example.com/book/v.mp4", "https://example.com/book/page.html", "#top", "mailto:a@b.c"}},
+		// 相对的 <base href> 相对于各自的域名
+		{LinkOptions{AssetBase: "https://cdn.bookstack.cn/"}, `<base href="book/">`, "https://www.bookstack.cn/", []string{
+			"https://cdn.bookstack.cn/a.png", "https://cdn.bookstack.cn/book/v.mp4", "https://www.bookstack.cn/book/page.html", "#top", "mailto:a@b.c"}},
+		{LinkOptions{IgnoreBaseTag: true}, `<base href="https://example.com/">`, "https://www.bookstack.cn/", []string{
+			"https://www.bookstack.cn/a.png", "https://www.bookstack.cn/v.mp4", "https://www.bookstack.cn/page.html", "#top", "mailto:a@b.c"}},
+		{LinkOptions{}, `<base href="javascript:alert(1)">`, "", []string{"/a.png", "v.mp4", "page.html", "#top", "mailto:a@b.c"}},
+	}
+	for i, c := range cases {
+		r := NewDefault().SetClassPrefix("").SetLinkOptions(c.opts)
+		htmlStr := "<html><head>" + c.head + "</head><body>" + body + "</body></html>"
+		nodes, err := r.Parse(htmlStr, c.domain)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := links(nodes); strings.Join(got, " ") != strings.Join(c.expect, " ") {
+			t.Errorf("case %v: unexpected links %v, expect %v", i, got, c.expect)
+		}
+
+		// 流式输出使用相同的规则
+		var buf bytes.Buffer
+		if err = r.Encode(&buf, strings.NewReader(htmlStr), EncodeOptions{Domain: c.domain}); err != nil {
+			t.Fatal(err)
+		}
+		if buf.String() != toJSON(nodes) {
+			t.Errorf("case %v: unexpected encoded nodes %v, expect %v", i, buf.String(), toJSON(nodes))
+		}
+	}
+
+	// 按照 <base href> 补全时保留链接中的编码，中文不编码
+	htmlStr := `<html><head><base href="https://example.com/book/"></head><body>` +
+		`<img src="a%20b.png"><a href="q%3Fx.html">q</a><a href="中文.html?k=%E4%B8%AD">c</a></body></html>`
+	nodes, err := NewDefault().SetClassPrefix("").Parse(htmlStr, "")
+	expect := []string{"https://example.com/book/a%20b.png", "https://example.com/book/q%3Fx.html", "https://example.com/book/中文.html?k=%E4%B8%AD"}
+	if got := links(nodes); err != nil || strings.Join(got, " ") != strings.Join(expect, " ") {
+		t.Errorf("unexpected escaped links %v %v, expect %v", got, err, expect)
+	}
+}
+
+// links 按照文档顺序返回 src 以及 href
+func links(nodes []Node) (items []string) {
+	for _, node := range nodes {
+		if src, ok := node.Attrs["src"]; ok {
+			items = append(items, src)
+		}
+		if href, ok := node.Attrs["href"]; ok {
+			items = append(items, href)
+		}
+		items = append(items, links(node.Children)...)
+	}
+	return
+}
@@ -0,0 +1,199 @@
+package html2json
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+	"syscall"
+)
+
+// NetPolicy 获取链接内容时的网络访问策略，用于防止通过 ParseByURL 访问内网服务(SSRF)。
+// 零值只允许 http、https 协议，并且禁止访问私有、回环、链路本地等地址
+type NetPolicy struct {
+	AllowHosts   []string // 允许访问的域名，不为空时只允许访问这些域名及其子域名
+	DenyHosts    []string // 禁止访问的域名及其子域名
+	AllowCIDRs   []string // 允许访问的网段或者 IP，优先于私有地址的限制，可用于放行内网中的服务或者代理
+	DenyCIDRs    []string // 禁止访问的网段或者 IP，优先于 AllowCIDRs
+	AllowPrivate bool     // 是否允许访问私有、回环、链路本地等地址
+}
+
+// 违反 NetPolicy 的原因，即 PolicyError 的 Code
+const (
+	PolicySchemeNotAllowed = "scheme_not_allowed" // 协议不是 http 或 https
+	PolicyHostDenied       = "host_denied"        // 域名在 DenyHosts 中
+	PolicyHostNotAllowed   = "host_not_allowed"   // 域名不在 AllowHosts 中
+	PolicyIPDenied         = "ip_denied"          // IP 在 DenyCIDRs 中
+	PolicyPrivateIP        = "private_ip"         // IP 为私有、回环、链路本地等地址
+)
+
+// PolicyError 访问的链接或者地址违反 NetPolicy 时返回的错误
+type PolicyError struct {
+	Code string
+	Host string // 链接中的域名，检查 IP 时可能为空
+	IP   string // 解析得到的 IP，检查域名时为空
+}
+
+func (e *PolicyError) Error() string {
+	target := e.Host
+	if e.IP != "" && e.IP != e.Host {
+		if target != "" {
+			target += " "
+		}
+		target += "(" + e.IP + ")"
+	}
+	return fmt.Sprintf("html2json: %v is blocked by net policy: %v", target, e.Code)
+}
+
+// 私有、回环、链路本地、运营商级 NAT 等不应从外部访问的网段，以及可以转换为任意 IPv4 地址的 NAT64 和 IPv4 兼容地址
+var privateNets = parseCIDRs(
+	"0.0.0.0/8", "10.0.0.0/8", "100.64.0.0/10", "127.0.0.0/8", "169.254.0.0/16", "172.16.0.0/12", "192.0.0.0/24",
+	"192.168.0.0/16", "198.18.0.0/15", "224.0.0.0/4", "240.0.0.0/4", "::/96", "::1/128", "64:ff9b::/96", "64:ff9b:1::/48",
+	"fc00::/7", "fe80::/10", "ff00::/8",
+)
+
+// 嵌入了 IPv4 地址的 IPv6 网段
+var (
+	nat64Net    = parseCIDR("64:ff9b::/96")
+	ipv4CompNet = parseCIDR("::/96")
+	sixToFour   = parseCIDR("2002::/16")
+)
+
+// embeddedIPv4 返回 NAT64、IPv4 兼容以及 6to4 地址中嵌入的 IPv4 地址，其他地址返回 nil
+func embeddedIPv4(ip net.IP) net.IP {
+	if len(ip) != net.IPv6len {
+		return nil
+	}
+	switch {
+	case nat64Net.Contains(ip) || ipv4CompNet.Contains(ip):
+		return ip[12:16]
+	case sixToFour.Contains(ip):
+		return ip[2:6]
+	}
+	return nil
+}
+
+func parseCIDRs(items ...string) (nets []*net.IPNet) {
+	for _, item := range items {
+		if n := parseCIDR(item); n != nil {
+			nets = append(nets, n)
+		}
+	}
+	return
+}
+
+// parseCIDR 解析网段，不带掩码的 IP 作为单个地址的网段
+func parseCIDR(item string) *net.IPNet {
+	item = strings.TrimSpace(item)
+	if !strings.Contains(item, "/") {
+		ip := net.ParseIP(item)
+		if ip == nil {
+			return nil
+		}
+		if ip4 := ip.To4(); ip4 != nil {
+			return &net.IPNet{IP: ip4, Mask: net.CIDRMask(32, 32)}
+		}
+		return &net.IPNet{IP: ip, Mask: net.CIDRMask(128, 128)}
+	}
+	_, n, err := net.ParseCIDR(item)
+	if err != nil {
+		return nil
+	}
+	return n
+}
+
+func containsIP(items []string, ip net.IP) bool {
+	for _, item := range items {
+		if n := parseCIDR(item); n != nil && n.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchHost 判断 host 是否为 domains 中的域名或者其子域名
+func matchHost(domains []string, host string) bool {
+	for _, domain := range domains {
+		domain = strings.ToLower(strings.Trim(strings.TrimSpace(domain), "."))
+		if domain != "" && (host == domain || strings.HasSuffix(host, "."+domain)) {
+			return true
+		}
+	}
+	return false
+}
+
+// CheckURL 检查链接的协议和域名，域名为 IP 时同时检查 IP
+func (p *NetPolicy) CheckURL(u *url.URL) error {
+	host := strings.ToLower(strings.TrimSuffix(u.Hostname(), "."))
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return &PolicyError{Code: PolicySchemeNotAllowed, Host: host}
+	}
+	if matchHost(p.DenyHosts, host) {
+		return &PolicyError{Code: PolicyHostDenied, Host: host}
+	}
+	if len(p.AllowHosts) > 0 && !matchHost(p.AllowHosts, host) {
+		return &PolicyError{Code: PolicyHostNotAllowed, Host: host}
+	}
+	if ip := net.ParseIP(host); ip != nil {
+		if err := p.CheckIP(ip); err != nil {
+			err.(*PolicyError).Host = host
+			return err
+		}
+	}
+	return nil
+}
+
+// CheckIP 检查解析得到的 IP。NAT64、IPv4 兼容以及 6to4 地址同时按照其中嵌入的 IPv4 地址检查，
+// 因此在 NAT64 网络中将 64:ff9b::/96 加入 AllowCIDRs 之后，仍然无法通过 NAT64 访问内网的 IPv4 地址
+func (p *NetPolicy) CheckIP(ip net.IP) error {
+	if ip4 := ip.To4(); ip4 != nil {
+		ip = ip4
+	}
+	if containsIP(p.DenyCIDRs, ip) {
+		return &PolicyError{Code: PolicyIPDenied, IP: ip.String()}
+	}
+	if ip4 := embeddedIPv4(ip); ip4 != nil {
+		if err := p.CheckIP(ip4); err != nil {
+			err.(*PolicyError).IP = ip.String()
+			return err
+		}
+	}
+	if containsIP(p.AllowCIDRs, ip) || p.AllowPrivate {
+		return nil
+	}
+	for _, n := range privateNets {
+		if n.Contains(ip) {
+			return &PolicyError{Code: PolicyPrivateIP, IP: ip.String()}
+		}
+	}
+	return nil
+}
+
+// resolve 解析域名并检查全部 IP，用于通过代理访问的链接
+func (p *NetPolicy) resolve(ctx context.Context, host string) error {
+	addrs, err := net.DefaultResolver.LookupIPAddr(ctx, host)
+	if err != nil {
+		return err
+	}
+	for _, addr := range addrs {
+		if err = p.CheckIP(addr.IP); err != nil {
+			err.(*PolicyError).Host = host
+			return err
+		}
+	}
+	return nil
+}
+
+// control 在建立连接之前检查 DNS 解析之后的 IP，每次重定向建立的连接都会检查
+func (p *NetPolicy) control(network, address string, c syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	ip := net.ParseIP(host)
+	if ip == nil {
+		return fmt.Errorf("html2json: invalid address %v", address)
+	}
+	return p.CheckIP(ip)
+}
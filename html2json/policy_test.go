@@ -0,0 +1,114 @@
+package html2json
+
+import (
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func TestNetPolicy_CheckIP(t *testing.T) {
+	p := &NetPolicy{AllowCIDRs: []string{"10.1.0.0/16"}, DenyCIDRs: []string{"8.8.8.0/24", "10.1.2.3"}}
+	cases := map[string]string{
+		"127.0.0.1":        PolicyPrivateIP,
+		"169.254.169.254":  PolicyPrivateIP,
+		"192.168.1.1":      PolicyPrivateIP,
+		"10.2.0.1":         PolicyPrivateIP,
+		"::1":              PolicyPrivateIP,
+		"fe80::1":          PolicyPrivateIP,
+		"fd00:ec2::254":    PolicyPrivateIP,
+		"::ffff:127.0.0.1": PolicyPrivateIP,
+		"10.1.0.1":         "",
+		"10.1.2.3":         PolicyIPDenied,
+		"8.8.8.8":          PolicyIPDenied,
+		"1.1.1.1":          "",
+		"2606:4700::1111":  "",
+		// NAT64、IPv4 兼容以及 6to4 地址
+		"64:ff9b::a9fe:a9fe":   PolicyPrivateIP,
+		"64:ff9b::808:808":     PolicyIPDenied,
+		"64:ff9b::101:101":     PolicyPrivateIP,
+		"64:ff9b:1::a00:1":     PolicyPrivateIP,
+		"::a9fe:a9fe":          PolicyPrivateIP,
+		"::101:101":            PolicyPrivateIP,
+		"2002:a9fe:a9fe::1":    PolicyPrivateIP,
+		"2002:7f00:1::":        PolicyPrivateIP,
+		"2002:808:808::1":      PolicyIPDenied,
+		"2002:a01:1::1":        "",
+		"2002:101:101::1":      "",
+		"2001:db8::a9fe:a9fe":  "",
+		"2002:a01:203:4::1234": PolicyIPDenied,
+	}
+	for ip, code := range cases {
+		err := p.CheckIP(net.ParseIP(ip))
+		var policyErr *PolicyError
+		if code == "" && err != nil || code != "" && (!errors.As(err, &policyErr) || policyErr.Code != code) {
+			t.Errorf("%v: unexpected error %v, expect %v", ip, err, code)
+		}
+	}
+
+	// NAT64 网络中放行 64:ff9b::/96，嵌入的内网 IPv4 地址仍然被禁止
+	p = &NetPolicy{AllowCIDRs: []string{"64:ff9b::/96"}}
+	for ip, code := range map[string]string{"64:ff9b::101:101": "", "64:ff9b::a9fe:a9fe": PolicyPrivateIP, "64:ff9b::7f00:1": PolicyPrivateIP} {
+		err := p.CheckIP(net.ParseIP(ip))
+		var policyErr *PolicyError
+		if code == "" && err != nil || code != "" && (!errors.As(err, &policyErr) || policyErr.Code != code) {
+			t.Errorf("%v: unexpected error %v with NAT64 allowed, expect %v", ip, err, code)
+		}
+	}
+}
+
+func TestNetPolicy_CheckURL(t *testing.T) {
+	p := &NetPolicy{AllowHosts: []string{"example.com", "bookstack.cn"}, DenyHosts: []string{"admin.example.com"}}
+	cases := map[string]string{
+		"https://example.com/a":         "",
+		"https://www.bookstack.cn/a":    "",
+		"https://admin.example.com/a":   PolicyHostDenied,
+		"https://notexample.com/a":      PolicyHostNotAllowed,
+		"ftp://example.com/a":           PolicySchemeNotAllowed,
+		"file:///etc/passwd":            PolicySchemeNotAllowed,
+		"http://127.0.0.1/":             PolicyHostNotAllowed,
+		"http://EXAMPLE.com./uppercase": "",
+	}
+	for link, code := range cases {
+		u, _ := url.Parse(link)
+		err := p.CheckURL(u)
+		var policyErr *PolicyError
+		if code == "" && err != nil || code != "" && (!errors.As(err, &policyErr) || policyErr.Code != code) {
+			t.Errorf("%v: unexpected error %v, expect %v", link, err, code)
+		}
+	}
+}
+
+func TestHTTPFetcher_policy(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if req.URL.Path == "/redirect" {
+			http.Redirect(w, req, req.URL.Query().Get("to"), http.StatusFound)
+			return
+		}
+		w.Write([]byte("<p>ok</p>"))
+	}))
+	defer server.Close()
+	port := server.URL[strings.LastIndex(server.URL, ":"):]
+
+	check := func(f *HTTPFetcher, link, code string) {
+		t.Helper()
+		_, err := NewDefault().SetFetcher(f).ParseByURL(link, "")
+		var policyErr *PolicyError
+		if code == "" && err != nil || code != "" && (!errors.As(err, &policyErr) || policyErr.Code != code) {
+			t.Errorf("%v: unexpected error %v, expect %v", link, err, code)
+		}
+	}
+
+	// 域名在建立连接时检查解析得到的 IP
+	check(NewFetcher(), "http://localhost"+port+"/", PolicyPrivateIP)
+
+	f := NewFetcher()
+	f.Policy = &NetPolicy{AllowCIDRs: []string{"127.0.0.1", "::1"}, DenyHosts: []string{"localhost"}}
+	check(f, server.URL+"/", "")
+	// 每次重定向都会检查
+	check(f, server.URL+"/redirect?to="+url.QueryEscape("http://localhost"+port+"/"), PolicyHostDenied)
+	check(f, server.URL+"/redirect?to="+url.QueryEscape("http://169.254.169.254/latest/meta-data/"), PolicyPrivateIP)
+}
@@ -0,0 +1,150 @@
+package html2json
+
+import (
+	"bufio"
+	"bytes"
+	"html"
+	"io"
+	"sort"
+	"strings"
+)
+
+// RenderOptions 将节点渲染为 HTML 的选项
+type RenderOptions struct {
+	StripClasses bool   // 去掉解析时自动生成的 tag-<name> 样式类
+	RestorePre   bool   // 将解析时由 pre 转换而来的 div 还原为 pre
+	ClassPrefix  string // 解析时使用的 class 前缀，默认为 DefaultClassPrefix
+}
+
+// 自闭合标签
+var voidTags = map[string]bool{
+	"area": true, "base": true, "br": true, "col": true, "embed": true, "hr": true, "img": true, "input": true,
+	"link": true, "meta": true, "param": true, "source": true, "track": true, "wbr": true,
+}
+
+// Render 将节点渲染为 HTML 并写入 w，文本和属性值均会被转义
+func Render(w io.Writer, nodes []Node, opts RenderOptions) error {
+	bw, ok := w.(*bufio.Writer)
+	if !ok {
+		bw = bufio.NewWriter(w)
+	}
+	for _, node := range nodes {
+		if err := render(bw, node, opts, false); err != nil {
+			return err
+		}
+	}
+	return bw.Flush()
+}
+
+// RenderString 将节点渲染为 HTML 字符串
+func RenderString(nodes []Node, opts RenderOptions) (string, error) {
+	var buf bytes.Buffer
+	if err := Render(&buf, nodes, opts); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
+
+// foreign 表示是否处于 svg 或 math 元素中，只有这种情况下属性名称才区分大小写
+func render(w *bufio.Writer, node Node, opts RenderOptions, foreign bool) (err error) {
+	if node.Type == "text" {
+		_, err = w.WriteString(html.EscapeString(node.Text))
+		return
+	}
+
+	if node.Name == "" {
+		for _, child := range node.Children {
+			if err = render(w, child, opts, foreign); err != nil {
+				return
+			}
+		}
+		return
+	}
+
+	name, attrs := restore(node, opts)
+	foreign = foreign || name == "svg" || name == "math"
+	w.WriteByte('<')
+	w.WriteString(name)
+	keys := make([]string, 0, len(attrs))
+	for key := range attrs {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		w.WriteByte(' ')
+		if foreign {
+			w.WriteString(key)
+		} else {
+			w.WriteString(strings.ToLower(key))
+		}
+		w.WriteString(`="`)
+		w.WriteString(html.EscapeString(attrs[key]))
+		w.WriteByte('"')
+	}
+
+	if voidTags[name] {
+		_, err = w.WriteString("/>")
+		return
+	}
+	w.WriteByte('>')
+
+	// 与 html.Render 一致，HTML 解析时会忽略 pre 等标签开头的第一个换行符
+	if (name == "pre" || name == "textarea" || name == "listing") && len(node.Children) > 0 {
+		if first := node.Children[0]; first.Type == "text" && strings.HasPrefix(first.Text, "\n") {
+			w.WriteByte('\n')
+		}
+	}
+
+	for _, child := range node.Children {
+		if err = render(w, child, opts, foreign); err != nil {
+			return
+		}
+	}
+
+	w.WriteString("</")
+	w.WriteString(name)
+	_, err = w.WriteString(">")
+	return
+}
+
+// restore 根据渲染选项还原节点的标签名称和属性
+func restore(node Node, opts RenderOptions) (name string, attrs map[string]string) {
+	name, attrs = node.Name, node.Attrs
+	if !opts.StripClasses && !opts.RestorePre {
+		return
+	}
+
+	attrs = make(map[string]string, len(node.Attrs))
+	for key, val := range node.Attrs {
+		attrs[key] = val
+	}
+
+	prefix := opts.ClassPrefix
+	if prefix == "" {
+		prefix = DefaultClassPrefix
+	}
+	classes := strings.Fields(attrs["class"])
+	generated := ""
+	if len(classes) > 0 && strings.HasPrefix(classes[0], prefix) {
+		generated = strings.TrimPrefix(classes[0], prefix)
+	}
+
+	// 未自动生成 class 时，根据默认的 pre 样式判断
+	if opts.RestorePre && name == "div" && (generated == "pre" || strings.HasPrefix(attrs["style"], preStyle)) {
+		name = "pre"
+		if style := strings.TrimPrefix(attrs["style"], preStyle); style != "" {
+			attrs["style"] = style
+		} else {
+			delete(attrs, "style")
+		}
+	}
+
+	if opts.StripClasses && generated != "" {
+		if len(classes) > 1 {
+			attrs["class"] = strings.Join(classes[1:], " ")
+		} else {
+			delete(attrs, "class")
+		}
+	}
+	return
+}
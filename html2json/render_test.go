@@ -0,0 +1,72 @@
+package html2json
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRender(t *testing.T) {
+	nodes, err := rt.Parse(`<p class="intro" title="a &amp; b">1 &lt; 2<br></p><pre>
+
+	code</pre>`, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		opts   RenderOptions
+		expect string
+	}{
+		{RenderOptions{}, `<p class="tag-p intro" title="a &amp; b">1 &lt; 2<br class="tag-br"/></p><div class="tag-pre" style="` + preStyle + `">` + "\n\tcode</div>"},
+		{RenderOptions{StripClasses: true, RestorePre: true}, `<p class="intro" title="a &amp; b">1 &lt; 2<br/></p><pre>` + "\n\n\tcode</pre>"},
+	}
+	for _, c := range cases {
+		got, err := RenderString(nodes, c.opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != c.expect {
+			t.Errorf("unexpected html:\n%v\n%v", got, c.expect)
+		}
+	}
+}
+
+// 解析 -> 渲染 -> 再解析 -> 再渲染，两次渲染的结果应当一致
+func TestRender_RoundTrip(t *testing.T) {
+	files, _ := filepath.Glob("examples/*.html")
+	mds, _ := filepath.Glob("examples/*.md")
+	opts := RenderOptions{StripClasses: true, RestorePre: true}
+	for _, file := range append(files, mds...) {
+		b, err := ioutil.ReadFile(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var nodes []Node
+		if strings.HasSuffix(file, ".md") {
+			nodes, err = rt.ParseMarkdownByByte(b, "")
+		} else {
+			nodes, err = rt.ParseByByte(b, "")
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		first, err := RenderString(nodes, opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if nodes, err = rt.Parse("<body>"+first+"</body>", ""); err != nil {
+			t.Fatal(err)
+		}
+		second, err := RenderString(nodes, opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if first != second {
+			t.Errorf("%v: round trip mismatch", file)
+		}
+	}
+}
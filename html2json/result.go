@@ -0,0 +1,196 @@
+package html2json
+
+import (
+	"bytes"
+	"context"
+	"net/url"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/russross/blackfriday"
+	"golang.org/x/net/html/atom"
+)
+
+// ParseResult 解析结果，除了节点之外还包括从节点中收集的图片、链接、标题、目录以及文本统计，
+// 可用于实现图片预览、目录等 rich-text 组件不支持的功能
+type ParseResult struct {
+	Nodes  []Node    `json:"nodes"`
+	Title  string    `json:"title,omitempty"`
+	Images []Image   `json:"images,omitempty"`
+	Links  []Link    `json:"links,omitempty"`
+	TOC    []TOCItem `json:"toc,omitempty"`
+	Stats  TextStats `json:"stats"`
+	// 被安全过滤移除的属性，按照文档顺序排列，NewParseResult 不会收集
+	Removed []Removed `json:"removed,omitempty"`
+}
+
+// Image 图片及其在节点树中的路径
+type Image struct {
+	Src  string `json:"src"`
+	Alt  string `json:"alt,omitempty"`
+	Path Path   `json:"path"`
+}
+
+// Link 指向文档之外的链接及其在节点树中的路径
+type Link struct {
+	Href string `json:"href"`
+	Text string `json:"text,omitempty"`
+	Path Path   `json:"path"`
+}
+
+// TextStats 文本统计
+type TextStats struct {
+	Chars int `json:"chars"` // 非空白字符数
+	Words int `json:"words"` // 词数，中日韩文字每个字算一个词
+}
+
+// NewParseResult 从节点中收集图片、链接、目录以及文本统计，标题为第一个 h1~h6 的文本。
+// site 为页面所在的站点，如 Parse 等方法的 domain，与站点域名相同的链接视为站内链接，不包括在 Links 中
+func NewParseResult(nodes []Node, site ...string) *ParseResult {
+	res := &ParseResult{Nodes: nodes, TOC: buildTOC(nodes)}
+	var siteHost string
+	if len(site) > 0 {
+		if u, err := url.Parse(site[0]); err == nil {
+			siteHost = linkHost(u)
+		}
+	}
+	Inspect(nodes, func(node *Node, path Path) WalkAction {
+		if node.Type == "text" {
+			res.Stats.add(node.Text)
+			return WalkContinue
+		}
+		switch node.Name {
+		case "img":
+			if src := node.Attrs["src"]; src != "" {
+				res.Images = append(res.Images, Image{Src: src, Alt: node.Attrs["alt"], Path: append(Path{}, path...)})
+			}
+		case "a":
+			if href := node.Attrs["href"]; isOutbound(href, siteHost) {
+				res.Links = append(res.Links, Link{Href: href, Text: collapseSpace(InnerText(node.Children)), Path: append(Path{}, path...)})
+			}
+		case "h1", "h2", "h3", "h4", "h5", "h6":
+			if res.Title == "" {
+				res.Title = collapseSpace(InnerText(node.Children))
+			}
+		}
+		return WalkContinue
+	})
+	return res
+}
+
+// isOutbound 判断链接是否指向其他站点，忽略 #fragment、javascript: 等链接，
+// 没有域名的相对链接(未指定 domain 时)以及域名为 siteHost 的链接视为站内链接
+func isOutbound(href, siteHost string) bool {
+	href = normalizeURL(href)
+	if href == "" || strings.HasPrefix(href, "#") {
+		return false
+	}
+	switch urlScheme(href) {
+	case "", "http", "https":
+		u, err := url.Parse(href)
+		return err == nil && u.Host != "" && linkHost(u) != siteHost
+	}
+	return false
+}
+
+// linkHost 返回小写的域名，去掉协议的默认端口
+func linkHost(u *url.URL) string {
+	host := strings.ToLower(u.Host)
+	if port := u.Port(); port == "80" && u.Scheme != "https" || port == "443" && u.Scheme != "http" {
+		host = strings.TrimSuffix(host, ":"+port)
+	}
+	return host
+}
+
+func (s *TextStats) add(text string) {
+	inWord := false
+	for _, c := range text {
+		if unicode.IsSpace(c) {
+			inWord = false
+			continue
+		}
+		s.Chars++
+		switch {
+		case unicode.In(c, unicode.Han, unicode.Hiragana, unicode.Katakana, unicode.Hangul):
+			s.Words++
+			inWord = false
+		case unicode.IsLetter(c) || unicode.IsDigit(c):
+			if !inWord {
+				s.Words++
+			}
+			inWord = true
+		default:
+			inWord = false
+		}
+	}
+}
+
+func (r *RichText) ParseResult(htmlStr, domain string) (*ParseResult, error) {
+	return r.ParseResultContext(context.Background(), htmlStr, domain)
+}
+
+// ParseResultContext 与 ParseContext 一致，返回带有图片、链接以及文本统计的解析结果，标题优先使用 <title>
+func (r *RichText) ParseResultContext(ctx context.Context, htmlStr, domain string) (*ParseResult, error) {
+	return r.ParseResultByByteContext(ctx, []byte(htmlStr), domain)
+}
+
+func (r *RichText) ParseResultByByte(htmlByte []byte, domain string) (*ParseResult, error) {
+	return r.ParseResultByByteContext(context.Background(), htmlByte, domain)
+}
+
+func (r *RichText) ParseResultByByteContext(ctx context.Context, htmlByte []byte, domain string) (*ParseResult, error) {
+	if err := r.limits.checkInput(int64(len(htmlByte))); err != nil {
+		return nil, err
+	}
+	htmlByte, err := r.decode(htmlByte, "")
+	if err != nil {
+		return nil, err
+	}
+	nodes, root, removed, err := r.parseReader(ctx, bytes.NewReader(htmlByte), domain)
+	if err != nil {
+		return nil, err
+	}
+	site := r.linkOptions.LinkBase
+	if site == "" {
+		site = domain
+	}
+	res := NewParseResult(nodes, site)
+	res.Removed = removed
+	if title := findElement(root, atom.Title); title != nil {
+		if text := collapseSpace(nodeText(title)); text != "" {
+			res.Title = text
+		}
+	}
+	return res, nil
+}
+
+func (r *RichText) ParseMarkdownResult(md, domain string) (*ParseResult, error) {
+	return r.ParseMarkdownResultContext(context.Background(), md, domain)
+}
+
+func (r *RichText) ParseMarkdownResultContext(ctx context.Context, md, domain string) (*ParseResult, error) {
+	if err := r.limits.checkInput(int64(len(md))); err != nil {
+		return nil, err
+	}
+	return r.ParseResultByByteContext(ctx, blackfriday.Run([]byte(md)), domain)
+}
+
+// ParseResultByURL 获取链接的 HTML 内容并解析，timeout 为超时时间(秒)，默认为 10 秒
+func (r *RichText) ParseResultByURL(urlStr, domain string, timeout ...int) (*ParseResult, error) {
+	to := 10 * time.Second
+	if len(timeout) > 0 && timeout[0] > 0 {
+		to = time.Duration(timeout[0]) * time.Second
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), to)
+	defer cancel()
+	return r.ParseResultByURLContext(ctx, urlStr, domain)
+}
+
+func (r *RichText) ParseResultByURLContext(ctx context.Context, urlStr, domain string) (result *ParseResult, err error) {
+	err = r.fetchParse(ctx, urlStr, domain, "result", &result, func(b []byte) (interface{}, error) {
+		result, err = r.ParseResultByByteContext(ctx, b, domain)
+		return result, err
+	})
+	return
+}
@@ -0,0 +1,56 @@
+package html2json
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestRichText_ParseResult(t *testing.T) {
+	htmlStr := `<html><head><title> html2json 使用说明 </title></head><body>` +
+		`<h1>标题</h1><p>Hello world, 你好世界！<img src="/a.png" alt="a"></p>` +
+		`<p><a href="#top">top</a><a href="https://gitee.com/truthhun"> TruthHun </a><a href="/docs/a.html">a</a><a href="//static.bookstack.cn/b.html">b</a><a href="mailto:a@b.c">mail</a><a href="javascript:void(0)">js</a></p>` +
+		`<div><img src="b.png"><img></div></body></html>`
+	res, err := rt.ParseResult(htmlStr, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Title != "html2json 使用说明" {
+		t.Errorf("unexpected title: %v", res.Title)
+	}
+	images := []Image{
+		{Src: "/a.png", Alt: "a", Path: Path{1, 1}},
+		{Src: "b.png", Path: Path{3, 0}},
+	}
+	if !reflect.DeepEqual(res.Images, images) {
+		t.Errorf("unexpected images: %v", toJSON(res.Images))
+	}
+	for _, img := range res.Images {
+		if Get(res.Nodes, img.Path).Attrs["src"] != img.Src {
+			t.Errorf("unexpected path of image %v", img.Src)
+		}
+	}
+	links := []Link{{Href: "https://gitee.com/truthhun", Text: "TruthHun", Path: Path{2, 1}}, {Href: "//static.bookstack.cn/b.html", Text: "b", Path: Path{2, 3}}}
+	if !reflect.DeepEqual(res.Links, links) {
+		t.Errorf("unexpected links: %v", toJSON(res.Links))
+	}
+	// 标题 2 + Hello world 2 + 你好世界 4 + top TruthHun a b mail js 6
+	if stats := (TextStats{Chars: 37, Words: 14}); res.Stats != stats {
+		t.Errorf("unexpected stats: %+v", res.Stats)
+	}
+
+	// 指定 domain 时相对链接补全为站内的绝对链接，与 domain 域名相同的链接不包括在内
+	htmlStr = `<a href="/docs/a.html">a</a><a href="https://WWW.bookstack.cn:443/b.html">b</a><a href="http://www.bookstack.cn:8080/c.html">c</a><a href="https://gitee.com/truthhun">d</a>`
+	if res, err = rt.ParseResult(htmlStr, "https://www.bookstack.cn/docs/"); err != nil {
+		t.Fatal(err)
+	}
+	links = []Link{{Href: "http://www.bookstack.cn:8080/c.html", Text: "c", Path: Path{2}}, {Href: "https://gitee.com/truthhun", Text: "d", Path: Path{3}}}
+	if !reflect.DeepEqual(res.Links, links) {
+		t.Errorf("unexpected links with domain: %v", toJSON(res.Links))
+	}
+
+	// 没有 <title> 时使用第一个标题
+	res, _ = rt.ParseMarkdownResult("text\n\n## 安装\n\n# 使用\n", "")
+	if res.Title != "安装" {
+		t.Errorf("unexpected title: %v", res.Title)
+	}
+}
@@ -0,0 +1,134 @@
+package html2json
+
+import (
+	"regexp"
+	"strings"
+)
+
+// 默认允许的 URL 协议，wxfile 和 cloud 为微信小程序的本地文件和云存储协议
+var DefaultSchemes = []string{"http", "https", "mailto", "tel", "wxfile", "cloud"}
+
+// 值为链接的属性
+var urlAttrs = map[string]bool{
+	"href": true, "src": true, "action": true, "formaction": true, "poster": true, "background": true, "cite": true,
+	"longdesc": true, "data": true, "lowsrc": true, "dynsrc": true, "codebase": true, "ping": true, "manifest": true,
+}
+
+var (
+	dataImageRegexp = regexp.MustCompile(`^data:image/(png|gif|jpe?g|webp|bmp);`)
+	styleRegexp     = regexp.MustCompile(`(?i)(expression\s*\(|javascript\s*:|vbscript\s*:|behavior\s*:|-moz-binding)`)
+)
+
+// Removed 被安全过滤移除的属性
+type Removed struct {
+	Tag    string `json:"tag"`
+	Attr   string `json:"attr"`
+	Value  string `json:"value"`
+	Reason string `json:"reason"`
+}
+
+// Sanitizer 对属性进行安全过滤：移除 on* 事件属性、危险的 style 以及协议不在白名单中的链接
+type Sanitizer struct {
+	Schemes         []string      // 允许的 URL 协议，不带协议的相对链接总是允许的
+	AllowDataImages bool          // 是否允许 img 使用 data:image/* 链接，svg 除外
+	OnRemove        func(Removed) // 属性被移除时回调，可用于记录日志
+}
+
+func NewSanitizer() *Sanitizer {
+	return &Sanitizer{
+		Schemes:         DefaultSchemes,
+		AllowDataImages: true,
+	}
+}
+
+// SetSanitizer 设置安全过滤规则，为 nil 时不进行过滤
+func (r *RichText) SetSanitizer(s *Sanitizer) *RichText {
+	r.sanitizer = s
+	return r
+}
+
+// Sanitize 过滤标签的属性，返回被移除的属性
+func (s *Sanitizer) Sanitize(tag string, attr map[string]string) (removed []Removed) {
+	for key, val := range attr {
+		reason := s.check(tag, strings.ToLower(key), val)
+		if reason == "" {
+			continue
+		}
+		delete(attr, key)
+		item := Removed{Tag: tag, Attr: key, Value: val, Reason: reason}
+		removed = append(removed, item)
+		if s.OnRemove != nil {
+			s.OnRemove(item)
+		}
+	}
+	return
+}
+
+// check 检查属性，不安全时返回原因
+func (s *Sanitizer) check(tag, key, val string) string {
+	switch {
+	case strings.HasPrefix(key, "on"):
+		return "event handler"
+	case key == "srcdoc":
+		return "inline document"
+	case key == "style":
+		if styleRegexp.MatchString(strings.ReplaceAll(val, "\\", "")) {
+			return "unsafe style"
+		}
+	case key == "srcset":
+		for _, item := range strings.Split(val, ",") {
+			if fields := strings.Fields(item); len(fields) > 0 && !s.AllowURL(tag, fields[0]) {
+				return "unsafe url"
+			}
+		}
+	case urlAttrs[key] || strings.HasSuffix(key, ":href"):
+		if !s.AllowURL(tag, val) {
+			return "unsafe url"
+		}
+	}
+	return ""
+}
+
+// AllowURL 判断链接的协议是否在白名单中
+func (s *Sanitizer) AllowURL(tag, link string) bool {
+	scheme := urlScheme(link)
+	if scheme == "" {
+		return true
+	}
+	if scheme == "data" {
+		return s.AllowDataImages && tag == "img" && dataImageRegexp.MatchString(strings.ToLower(normalizeURL(link)))
+	}
+	for _, allow := range s.Schemes {
+		if strings.EqualFold(allow, scheme) {
+			return true
+		}
+	}
+	return false
+}
+
+// normalizeURL 与浏览器一致，去掉链接首尾的空白和控制字符，以及其中的 tab 和换行
+func normalizeURL(link string) string {
+	link = strings.TrimFunc(link, func(r rune) bool { return r <= ' ' })
+	return strings.Map(func(r rune) rune {
+		if r == '\t' || r == '\n' || r == '\r' {
+			return -1
+		}
+		return r
+	}, link)
+}
+
+// urlScheme 返回链接的协议(小写)，相对链接返回空字符串
+func urlScheme(link string) string {
+	link = normalizeURL(link)
+	for i, c := range link {
+		switch {
+		case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z':
+		case i > 0 && (c >= '0' && c <= '9' || c == '+' || c == '-' || c == '.'):
+		case i > 0 && c == ':':
+			return strings.ToLower(link[:i])
+		default:
+			return ""
+		}
+	}
+	return ""
+}
@@ -0,0 +1,57 @@
+package html2json
+
+import (
+	"sort"
+	"strings"
+	"testing"
+)
+
+func TestSanitizer(t *testing.T) {
+	var removed []string
+	s := NewSanitizer()
+	s.OnRemove = func(item Removed) {
+		removed = append(removed, item.Tag+"."+item.Attr+":"+item.Reason)
+	}
+	r := NewDefault().SetSanitizer(s)
+
+	htmlStr := `<a href="java&#x09;script:alert(1)" onclick="alert(2)">x</a>` +
+		`<a href="mailto:me@example.com">mail</a>` +
+		`<img src="data:image/png;base64,AAAA" onerror="alert(3)">` +
+		`<img src="data:text/html;base64,AAAA">` +
+		`<p style="width: expression(alert(4))">p</p>` +
+		`<a href=" VBScript:msgbox(1)">vb</a>`
+	nodes, err := r.Parse(htmlStr, "https://www.bookstack.cn")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expect := `[{"name":"a","attrs":{"class":"tag-a"},"children":[{"type":"text","text":"x"}]},` +
+		`{"name":"a","attrs":{"class":"tag-a","href":"mailto:me@example.com"},"children":[{"type":"text","text":"mail"}]},` +
+		`{"name":"img","attrs":{"class":"tag-img","src":"data:image/png;base64,AAAA"}},` +
+		`{"name":"img","attrs":{"class":"tag-img"}},` +
+		`{"name":"p","attrs":{"class":"tag-p"},"children":[{"type":"text","text":"p"}]},` +
+		`{"name":"a","attrs":{"class":"tag-a"},"children":[{"type":"text","text":"vb"}]}]`
+	if got := toJSON(nodes); got != expect {
+		t.Errorf("unexpected nodes:\n%v\n%v", got, expect)
+	}
+
+	sort.Strings(removed)
+	expectRemoved := "a.href:unsafe url,a.href:unsafe url,a.onclick:event handler,img.onerror:event handler,img.src:unsafe url,p.style:unsafe style"
+	if got := strings.Join(removed, ","); got != expectRemoved {
+		t.Errorf("unexpected removed:\n%v\n%v", got, expectRemoved)
+	}
+
+	// ParseResult 按照文档顺序返回被移除的属性
+	res, err := NewDefault().ParseResult(htmlStr, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	removed = removed[:0]
+	for _, item := range res.Removed {
+		removed = append(removed, item.Tag+"."+item.Attr+":"+item.Reason)
+	}
+	expectRemoved = "a.href:unsafe url,a.onclick:event handler,img.onerror:event handler,img.src:unsafe url,p.style:unsafe style,a.href:unsafe url"
+	if got := strings.Join(removed, ","); got != expectRemoved {
+		t.Errorf("unexpected removed of result:\n%v\n%v", got, expectRemoved)
+	}
+}
@@ -0,0 +1,91 @@
+package html2json
+
+import (
+	"strings"
+
+	"github.com/andybalholm/cascadia"
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// SetSelectors 设置需要转换的元素的 CSS 选择器，匹配到的元素(包括元素本身)按照在文档中的顺序转换，
+// 不设置时转换 body 的全部内容
+func (r *RichText) SetSelectors(selectors ...string) *RichText {
+	r.selectors = trimSelectors(selectors)
+	return r
+}
+
+// SetExcludes 设置不需要转换的元素的 CSS 选择器，如导航、广告以及评论等
+func (r *RichText) SetExcludes(selectors ...string) *RichText {
+	r.excludes = trimSelectors(selectors)
+	return r
+}
+
+func trimSelectors(selectors []string) (items []string) {
+	for _, selector := range selectors {
+		if selector = strings.TrimSpace(selector); selector != "" {
+			items = append(items, selector)
+		}
+	}
+	return
+}
+
+// scope 移除需要排除的元素，并将 body 的内容替换为选择器匹配到的元素
+func (r *RichText) scope(root *html.Node) error {
+	if len(r.excludes) > 0 {
+		sel, err := cascadia.Compile(strings.Join(r.excludes, ","))
+		if err != nil {
+			return err
+		}
+		for _, node := range sel.MatchAll(root) {
+			if node.Parent != nil {
+				node.Parent.RemoveChild(node)
+			}
+		}
+	}
+
+	if len(r.selectors) == 0 {
+		return nil
+	}
+
+	sel, err := cascadia.Compile(strings.Join(r.selectors, ","))
+	if err != nil {
+		return err
+	}
+	body := findElement(root, atom.Body)
+	if body == nil {
+		return nil
+	}
+
+	var matched []*html.Node
+	for _, node := range sel.MatchAll(body) {
+		if node == body {
+			return nil
+		}
+		// MatchAll 按照文档顺序返回，祖先元素已匹配的元素无需重复转换
+		if len(matched) == 0 || !contains(matched[len(matched)-1], node) {
+			matched = append(matched, node)
+		}
+	}
+
+	for _, node := range matched {
+		node.Parent.RemoveChild(node)
+	}
+	for body.FirstChild != nil {
+		body.RemoveChild(body.FirstChild)
+	}
+	for _, node := range matched {
+		body.AppendChild(node)
+	}
+	return nil
+}
+
+// contains 判断 node 是否为 ancestor 的子孙节点
+func contains(ancestor, node *html.Node) bool {
+	for p := node.Parent; p != nil; p = p.Parent {
+		if p == ancestor {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,35 @@
+package html2json
+
+import "testing"
+
+func TestRichText_SetSelectors(t *testing.T) {
+	htmlStr := `<nav>menu</nav><div class="article"><h1>title</h1><div class="ads">ads</div><p>content</p></div>` +
+		`<div class="comments">comments</div><p class="article">more</p>`
+	cases := []struct {
+		r      *RichText
+		expect string
+	}{
+		{rt.Clone().SetClassPrefix("").SetSelectors(".article").SetExcludes(".ads", "nav"),
+			`[{"name":"div","attrs":{"class":"article"},"children":[{"name":"h1","children":[{"type":"text","text":"title"}]},{"name":"p","children":[{"type":"text","text":"content"}]}]},{"name":"p","attrs":{"class":"article"},"children":[{"type":"text","text":"more"}]}]`},
+		{rt.Clone().SetClassPrefix("").SetSelectors("h1, p"),
+			`[{"name":"h1","children":[{"type":"text","text":"title"}]},{"name":"p","children":[{"type":"text","text":"content"}]},{"name":"p","attrs":{"class":"article"},"children":[{"type":"text","text":"more"}]}]`},
+		{rt.Clone().SetClassPrefix("").SetEngine(EngineNative).SetExcludes("nav, .article, .comments"), `null`},
+	}
+	for _, c := range cases {
+		nodes, err := c.r.Parse(htmlStr, "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := toJSON(nodes); got != c.expect {
+			t.Errorf("unexpected nodes:\n%v\n%v", got, c.expect)
+		}
+	}
+
+	if _, err := rt.Clone().SetSelectors("div[").Parse(htmlStr, ""); err == nil {
+		t.Error("expect error for invalid selector")
+	}
+	// Clone 不影响原有的配置
+	if nodes, _ := rt.Parse(`<nav>menu</nav>`, ""); len(nodes) != 1 {
+		t.Errorf("unexpected nodes: %v", toJSON(nodes))
+	}
+}
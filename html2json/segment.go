@@ -0,0 +1,387 @@
+package html2json
+
+import (
+	"strconv"
+	"strings"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// Segment ParseByByteV2 输出的片段。Type 为 richtext 时 Data 为 rich-text 的节点；
+// 为 audio、video、iframe、img 时 Data 为媒体节点，可以使用小程序的原生组件渲染；
+// 为 code、table、formula 时 Data 为代码块、表格以及公式的节点，同样可以使用 rich-text 渲染
+type Segment struct {
+	Type string `json:"type"`
+	Data []Node `json:"data"`
+	// 切分点所在的外层元素，由外到内，不包括子节点，可用于还原切分点的样式
+	Wrappers []Node `json:"wrappers,omitempty"`
+	Media    *Media `json:"media,omitempty"`
+	Lang     string `json:"lang,omitempty"` // 代码的语言
+	Text     string `json:"text,omitempty"` // 代码的文本，或者公式的 TeX 源码
+}
+
+// Media 从 audio、video、iframe、img 中提取的属性
+type Media struct {
+	Src      string `json:"src,omitempty"`
+	Poster   string `json:"poster,omitempty"`
+	Width    string `json:"width,omitempty"`
+	Height   string `json:"height,omitempty"`
+	Controls bool   `json:"controls,omitempty"`
+	Autoplay bool   `json:"autoplay,omitempty"`
+	Loop     bool   `json:"loop,omitempty"`
+	Muted    bool   `json:"muted,omitempty"`
+}
+
+// 单独作为一个片段的图片以及公式的父元素
+var segmentBlockTags = map[string]bool{"article": true, "aside": true, "base": true, "body": true, "center": true, "figure": true, "nav": true, "title": true, "h1": true, "h2": true, "h3": true, "h4": true, "h5": true, "h6": true, "p": true, "div": true}
+
+// segmenter 在媒体、代码块、表格以及公式处切分节点树，切分点前后的内容复制切分点的外层元素，保持原有的结构
+type segmenter struct {
+	c        *converter
+	segments []Segment
+
+	cuts   map[*html.Node]string // 切分点及其片段类型
+	inside map[*html.Node]bool   // 包含切分点的元素
+
+	body    *html.Node   // 当前 richtext 片段
+	chain   []*html.Node // 当前片段中复制的外层元素
+	opened  []*html.Node // chain 对应的原始元素
+	content bool         // 当前片段中是否有内容
+
+	started  map[*html.Node]bool // 已经复制过的外层元素
+	items    map[*html.Node]int  // ol 中已经输出的 li 数量
+	wrappers map[*html.Node]Node // 转换过的外层元素，不包括子节点
+}
+
+func (c *converter) segment(body *html.Node) []Segment {
+	s := &segmenter{
+		c:        c,
+		cuts:     make(map[*html.Node]string),
+		inside:   make(map[*html.Node]bool),
+		started:  make(map[*html.Node]bool),
+		items:    make(map[*html.Node]int),
+		wrappers: make(map[*html.Node]Node),
+	}
+	s.mark(body)
+	s.split(body, nil)
+	s.flush()
+	return s.segments
+}
+
+// mark 标记 parent 中的切分点，media 表示其中是否有媒体。包含媒体的代码块、表格以及公式在媒体处切分
+func (s *segmenter) mark(parent *html.Node) (cut, media bool) {
+	for child := parent.FirstChild; child != nil; child = child.NextSibling {
+		if child.Type != html.ElementNode {
+			continue
+		}
+		if kind := mediaType(child); kind != "" {
+			s.cuts[child] = kind
+			cut, media = true, true
+			continue
+		}
+		c, m := s.mark(child)
+		if kind := blockType(child); kind != "" && !m {
+			s.cuts[child] = kind
+			cut = true
+			continue
+		}
+		if c {
+			s.inside[child] = true
+			cut, media = true, media || m
+		}
+	}
+	return
+}
+
+// mediaType 返回媒体的片段类型。图片只有在块元素中并且没有其他文本时才单独作为一个片段
+func mediaType(node *html.Node) string {
+	switch node.DataAtom {
+	case atom.Audio, atom.Video, atom.Iframe:
+		return node.Data
+	case atom.Img:
+		if blockParent(node) && strings.TrimSpace(nodeText(node.Parent)) == "" {
+			return node.Data
+		}
+	}
+	return ""
+}
+
+// blockType 返回代码块、表格以及独立显示的公式的片段类型
+func blockType(node *html.Node) string {
+	switch {
+	case node.DataAtom == atom.Pre:
+		return "code"
+	case node.DataAtom == atom.Table:
+		return "table"
+	case isFormula(node):
+		return "formula"
+	}
+	return ""
+}
+
+// isFormula 判断元素是否为独立显示的公式：display 为 block 的 MathML、KaTeX、MathJax 以及 type 为 math/tex 的 script，
+// 或者块元素中只有该公式
+func isFormula(node *html.Node) bool {
+	display := false
+	switch {
+	case node.DataAtom == atom.Math:
+		display = getAttr(node, "display") == "block"
+	case node.DataAtom == atom.Script:
+		typ := strings.ToLower(getAttr(node, "type"))
+		if !strings.HasPrefix(typ, "math/tex") {
+			return false
+		}
+		display = strings.Contains(typ, "mode=display")
+	case node.Data == "mjx-container":
+		display = getAttr(node, "display") == "true"
+	default:
+		classes := strings.Fields(getAttr(node, "class"))
+		if hasClass(classes, "katex-display") {
+			return true
+		}
+		if !hasClass(classes, "katex") {
+			return false
+		}
+	}
+	return display || blockParent(node) && onlyChild(node)
+}
+
+func hasClass(classes []string, class string) bool {
+	for _, c := range classes {
+		if c == class {
+			return true
+		}
+	}
+	return false
+}
+
+func blockParent(node *html.Node) bool {
+	tag := "body"
+	if node.Parent != nil {
+		tag = strings.ToLower(node.Parent.DataAtom.String())
+	}
+	return segmentBlockTags[tag]
+}
+
+// onlyChild 判断节点的兄弟节点是否都是空白文本或者注释
+func onlyChild(node *html.Node) bool {
+	for item := node.Parent.FirstChild; item != nil; item = item.NextSibling {
+		if item != node && !blankNode(item) {
+			return false
+		}
+	}
+	return true
+}
+
+func blankNode(node *html.Node) bool {
+	switch node.Type {
+	case html.TextNode:
+		return strings.TrimSpace(node.Data) == ""
+	case html.CommentNode:
+		return true
+	}
+	return false
+}
+
+// split 按照文档顺序处理 parent 的子节点，wrappers 为 parent 及其外层元素
+func (s *segmenter) split(parent *html.Node, wrappers []*html.Node) {
+	for child := parent.FirstChild; child != nil && s.c.err == nil; {
+		next := child.NextSibling
+		switch {
+		case s.cuts[child] != "":
+			s.flush()
+			parent.RemoveChild(child)
+			s.emit(s.cuts[child], child, wrappers)
+		case s.inside[child]:
+			s.split(child, append(wrappers[:len(wrappers):len(wrappers)], child))
+		default:
+			parent.RemoveChild(child)
+			s.open(wrappers).AppendChild(child)
+			if !blankNode(child) {
+				s.content = true
+			}
+			if parent.DataAtom == atom.Ol && child.DataAtom == atom.Li {
+				s.items[parent]++
+			}
+		}
+		child = next
+	}
+}
+
+// open 返回当前片段中与 wrappers 对应的元素，不存在时复制外层元素，复制的后续部分不保留 id。
+// 被切分的 li 的后续部分不显示列表符号，ol 的后续部分设置 start 保持编号连续
+func (s *segmenter) open(wrappers []*html.Node) *html.Node {
+	if s.body == nil {
+		s.body = &html.Node{Type: html.ElementNode, Data: "body", DataAtom: atom.Body}
+	}
+	parent := s.body
+	for i, w := range wrappers {
+		if i < len(s.chain) && s.opened[i] == w {
+			parent = s.chain[i]
+			continue
+		}
+		s.chain, s.opened = s.chain[:i], s.opened[:i]
+		s.wrapper(w)
+		clone := &html.Node{Type: w.Type, Data: w.Data, DataAtom: w.DataAtom, Namespace: w.Namespace, Attr: append([]html.Attribute{}, w.Attr...)}
+		if s.started[w] {
+			removeAttr(clone, "id")
+		}
+		switch {
+		case w.DataAtom == atom.Li && s.started[w]:
+			setAttr(clone, "style", "list-style-type: none;"+getAttr(w, "style"))
+		case w.DataAtom == atom.Ol && s.items[w] > 0:
+			start, err := strconv.Atoi(getAttr(w, "start"))
+			if err != nil {
+				start = 1
+			}
+			start += s.items[w]
+			// 第一个 li 为被切分的 li 的后续部分
+			if i+1 < len(wrappers) && s.started[wrappers[i+1]] {
+				start--
+			}
+			setAttr(clone, "start", strconv.Itoa(start))
+		case w.DataAtom == atom.Li && w.Parent != nil && w.Parent.DataAtom == atom.Ol:
+			s.items[w.Parent]++
+		}
+		s.started[w] = true
+		parent.AppendChild(clone)
+		s.chain, s.opened = append(s.chain, clone), append(s.opened, w)
+		parent = clone
+	}
+	return parent
+}
+
+// flush 转换当前的 richtext 片段，只有空白文本以及外层元素的片段会被忽略
+func (s *segmenter) flush() {
+	if s.body != nil && s.content {
+		if data := s.c.convert(s.body); len(data) > 0 {
+			s.segments = append(s.segments, Segment{Type: "richtext", Data: data})
+		}
+	}
+	s.body, s.chain, s.opened, s.content = nil, nil, nil, false
+}
+
+// wrapper 转换外层元素本身并缓存结果。第一次转换时从原始元素中移除不安全的属性，
+// 之后复制的外层元素不再重复触发安全过滤
+func (s *segmenter) wrapper(w *html.Node) (Node, bool) {
+	if h, ok := s.wrappers[w]; ok {
+		return h, h.Name != ""
+	}
+	n := len(s.c.removed)
+	h, _, ok := s.c.shell(w)
+	for _, item := range s.c.removed[n:] {
+		removeAttr(w, item.Attr)
+	}
+	h.Children = nil
+	if !ok {
+		h = Node{}
+	}
+	s.wrappers[w] = h
+	return h, ok
+}
+
+// emit 转换切分点并输出对应类型的片段
+func (s *segmenter) emit(kind string, node *html.Node, wrappers []*html.Node) {
+	body := &html.Node{Type: html.ElementNode, Data: "body", DataAtom: atom.Body}
+	body.AppendChild(node)
+	seg := Segment{Type: kind, Data: s.c.convert(body)}
+	for _, w := range wrappers {
+		if h, ok := s.wrapper(w); ok {
+			seg.Wrappers = append(seg.Wrappers, h)
+		}
+	}
+	switch kind {
+	case "audio", "video", "iframe", "img":
+		seg.Media = s.c.media(node, seg.Data)
+	case "code":
+		seg.Text = nodeText(node)
+		for item := node.FirstChild; item != nil && seg.Lang == ""; item = item.NextSibling {
+			if item.DataAtom == atom.Code {
+				seg.Lang = codeLanguage(item)
+			}
+		}
+		if seg.Lang == "" {
+			seg.Lang = codeLanguage(node)
+		}
+	case "formula":
+		seg.Text = formulaSource(node)
+	}
+	if len(seg.Data) == 0 && seg.Media == nil && seg.Text == "" {
+		return
+	}
+	s.segments = append(s.segments, seg)
+}
+
+// media 提取媒体的属性，src 优先使用转换之后的链接，没有 src 时使用第一个 <source> 的链接
+func (c *converter) media(node *html.Node, data []Node) *Media {
+	r := c.r
+	m := &Media{
+		Width:    getAttr(node, "width"),
+		Height:   getAttr(node, "height"),
+		Controls: hasAttr(node, "controls"),
+		Autoplay: hasAttr(node, "autoplay"),
+		Loop:     hasAttr(node, "loop"),
+		Muted:    hasAttr(node, "muted"),
+	}
+	for _, decl := range parseDeclarations(getAttr(node, "style")) {
+		switch {
+		case decl.prop == "width" && m.Width == "":
+			m.Width = decl.value
+		case decl.prop == "height" && m.Height == "":
+			m.Height = decl.value
+		}
+	}
+	if len(data) == 1 && data[0].Name == node.Data {
+		m.Src = data[0].Attrs["src"]
+	}
+	attr := map[string]string{"poster": getAttr(node, "poster")}
+	if m.Src == "" {
+		attr["src"] = strings.TrimSpace(getAttr(node, "src"))
+		for item := node.FirstChild; item != nil && attr["src"] == ""; item = item.NextSibling {
+			if item.DataAtom == atom.Source {
+				attr["src"] = strings.TrimSpace(getAttr(item, "src"))
+			}
+		}
+	}
+	// 媒体本身已经在转换时经过安全过滤，这里只检查链接，不重复触发 OnRemove
+	allow := func(link string) bool {
+		return link != "" && (r.sanitizer == nil || r.sanitizer.AllowURL(node.Data, link))
+	}
+	if src := attr["src"]; allow(src) {
+		m.Src = c.assetLink(src)
+	}
+	if poster := strings.TrimSpace(attr["poster"]); allow(poster) {
+		m.Poster = c.assetLink(poster)
+	}
+	return m
+}
+
+func hasAttr(node *html.Node, key string) bool {
+	_, ok := findAttr(node, key)
+	return ok
+}
+
+// formulaSource 返回公式的 TeX 源码：KaTeX 以及 MathML 中的 annotation、math/tex 的 script 以及 math 的 alttext
+func formulaSource(node *html.Node) (tex string) {
+	if node.DataAtom == atom.Script {
+		return strings.TrimSpace(nodeText(node))
+	}
+	eachElement(node, func(item *html.Node) bool {
+		if tex != "" {
+			return false
+		}
+		if item.DataAtom == atom.Annotation && strings.EqualFold(getAttr(item, "encoding"), "application/x-tex") {
+			tex = strings.TrimSpace(nodeText(item))
+		}
+		return true
+	})
+	if tex == "" {
+		if alt := getAttr(node, "alttext"); alt != "" {
+			tex = alt
+		} else if math := findElement(node, atom.Math); math != nil {
+			tex = getAttr(math, "alttext")
+		}
+	}
+	return
+}
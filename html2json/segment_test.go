@@ -0,0 +1,92 @@
+package html2json
+
+import (
+	"sort"
+	"testing"
+)
+
+func TestParseByByteV2_wrappers(t *testing.T) {
+	htmlStr := `<ol><li>one</li><li>two<video src="v.mp4" poster="p.jpg" controls width="320"></video>tail</li><li>three</li></ol>`
+	segments, err := rt.Clone().SetClassPrefix("").ParseByByteV2([]byte(htmlStr), "https://a.com/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expect := `[{"type":"richtext","data":[{"name":"ol","children":[{"name":"li","children":[{"type":"text","text":"one"}]},{"name":"li","children":[{"type":"text","text":"two"}]}]}]},` +
+		`{"type":"video","data":[{"name":"video","attrs":{"controls":"","poster":"p.jpg","src":"https://a.com/v.mp4","width":"320"}}],"wrappers":[{"name":"ol"},{"name":"li"}],` +
+		`"media":{"src":"https://a.com/v.mp4","poster":"https://a.com/p.jpg","width":"320","controls":true}},` +
+		`{"type":"richtext","data":[{"name":"ol","attrs":{"start":"2"},"children":[{"name":"li","attrs":{"style":"list-style-type: none;"},"children":[{"type":"text","text":"tail"}]},{"name":"li","children":[{"type":"text","text":"three"}]}]}]}]`
+	if got := toJSON(segments); got != expect {
+		t.Errorf("unexpected segments:\n%v\n%v", got, expect)
+	}
+}
+
+func TestParseByByteV2_types(t *testing.T) {
+	htmlStr := `<p>text</p><pre><code class="language-go">x := 1</code></pre><table><tr><td>a</td></tr></table>` +
+		`<p><math display="block"><semantics><mi>x</mi><annotation encoding="application/x-tex">x^2</annotation></semantics></math></p>` +
+		`<p>inline <span class="katex">y</span></p><table><tr><td><audio><source src="a.mp3"></audio></td></tr></table>`
+	segments, err := rt.ParseByByteV2([]byte(htmlStr), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var types []string
+	for _, seg := range segments {
+		types = append(types, seg.Type)
+	}
+	// 行内公式不切分，包含媒体的表格在媒体处切分
+	expect := `["richtext","code","table","formula","richtext","audio"]`
+	if got := toJSON(types); got != expect {
+		t.Fatalf("unexpected types: %v", toJSON(segments))
+	}
+	if seg := segments[1]; seg.Lang != "go" || seg.Text != "x := 1" {
+		t.Errorf("unexpected code: %v", toJSON(seg))
+	}
+	if seg := segments[3]; seg.Text != "x^2" || len(seg.Wrappers) != 1 || seg.Wrappers[0].Name != "p" {
+		t.Errorf("unexpected formula: %v", toJSON(seg))
+	}
+	if seg := segments[5]; seg.Media == nil || seg.Media.Src != "a.mp3" || len(seg.Wrappers) != 4 {
+		t.Errorf("unexpected audio: %v", toJSON(seg))
+	}
+}
+
+func TestParseByByteV2_sanitize(t *testing.T) {
+	var removed []string
+	s := NewSanitizer()
+	s.OnRemove = func(item Removed) {
+		removed = append(removed, item.Tag+" "+item.Attr)
+	}
+	htmlStr := `<div onclick="a()"><p>before</p><video src="v.mp4" poster="javascript:alert(1)" onplay="b()"></video>` +
+		`<p>middle</p><audio src="a.mp3"></audio><p>after</p></div>`
+	segments, err := rt.Clone().SetClassPrefix("").SetSanitizer(s).ParseByByteV2([]byte(htmlStr), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 外层元素以及媒体的属性只过滤一次
+	sort.Strings(removed)
+	if expect := `["div onclick","video onplay","video poster"]`; toJSON(removed) != expect {
+		t.Errorf("unexpected removed: %v", toJSON(removed))
+	}
+	expect := `[{"type":"richtext","data":[{"name":"div","children":[{"name":"p","children":[{"type":"text","text":"before"}]}]}]},` +
+		`{"type":"video","data":[{"name":"video","attrs":{"src":"v.mp4"}}],"wrappers":[{"name":"div"}],"media":{"src":"v.mp4"}},` +
+		`{"type":"richtext","data":[{"name":"div","children":[{"name":"p","children":[{"type":"text","text":"middle"}]}]}]},` +
+		`{"type":"audio","data":[{"name":"audio","attrs":{"src":"a.mp3"}}],"wrappers":[{"name":"div"}],"media":{"src":"a.mp3"}},` +
+		`{"type":"richtext","data":[{"name":"div","children":[{"name":"p","children":[{"type":"text","text":"after"}]}]}]}]`
+	if got := toJSON(segments); got != expect {
+		t.Errorf("unexpected segments:\n%v\n%v", got, expect)
+	}
+}
+
+func TestParseByByteV2_blank(t *testing.T) {
+	// 媒体之间只有空白文本时不输出 richtext 片段
+	htmlStr := "<body>\n  <img src=\"a.png\">\n\n<video src=\"v.mp4\"></video>\n\n<audio src=\"a.mp3\"></audio>\n</body>"
+	segments, err := rt.ParseByByteV2([]byte(htmlStr), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var types []string
+	for _, seg := range segments {
+		types = append(types, seg.Type)
+	}
+	if expect := `["img","video","audio"]`; toJSON(types) != expect {
+		t.Errorf("unexpected segments: %v", toJSON(segments))
+	}
+}
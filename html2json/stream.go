@@ -0,0 +1,890 @@
+package html2json
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/russross/blackfriday"
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// EncodeOptions 流式输出 JSON 的选项
+type EncodeOptions struct {
+	Domain   string // 图片等静态资源域名，与 Parse 的 domain 参数一致
+	Markdown bool   // 输入内容是否为 markdown
+}
+
+// ErrEncodeOption Encode 不支持当前 RichText 的设置，见 CheckEncode
+var ErrEncodeOption = errors.New("html2json: option is not supported by Encode")
+
+// Encode 边解析 HTML 边将节点数组以 JSON 的形式写入 w，不在内存中构造完整的节点树，
+// 内存占用与嵌套层级相关而与文档大小无关。
+//
+// 与 Parse 使用相同的标签、属性、class 以及转换规则，并按照 HTML5 的规则补全 tbody、恢复被提前结束的 b、i 等格式化元素、
+// 处理 svg 等外部内容。已经输出的元素无法再移动，因此有两种情况与 Parse 不同：表格中单元格之外的内容不会被移到表格之前，
+// 跨越块级元素的格式化元素(如 <b>1<p>2</b>3</p>)会与其中的块级元素一同结束。
+// 字符集根据内容开头的 1024 字节检测。注册了转换函数的元素以及 <picture> 会先在内存中构造该元素的子树，再转换并输出。
+// SetSelectors 等需要完整节点树的设置不支持，见 CheckEncode。
+// 出错时会补全已输出的 JSON 结构后返回错误
+func (r *RichText) Encode(w io.Writer, rd io.Reader, opts EncodeOptions) error {
+	return r.EncodeContext(context.Background(), w, rd, opts)
+}
+
+// CheckEncode 检查能否使用 Encode：设置了 SetSelectors、SetExcludes、SetWhitespace(WhitespaceNormal)、SetInlineStyles
+// 或者 SetHeadingIDs 时返回 ErrEncodeOption，这些设置需要完整的节点树。Encode 在输出之前同样会检查
+func (r *RichText) CheckEncode() error {
+	var options []string
+	if len(r.selectors) > 0 {
+		options = append(options, "selectors")
+	}
+	if len(r.excludes) > 0 {
+		options = append(options, "excludes")
+	}
+	if r.whitespace == WhitespaceNormal {
+		options = append(options, "whitespace")
+	}
+	if r.inlineStyles {
+		options = append(options, "inline styles")
+	}
+	if r.headingIDs {
+		options = append(options, "heading ids")
+	}
+	if len(options) > 0 {
+		return fmt.Errorf("%w: %v", ErrEncodeOption, strings.Join(options, ", "))
+	}
+	return nil
+}
+
+func (r *RichText) EncodeContext(ctx context.Context, w io.Writer, rd io.Reader, opts EncodeOptions) (err error) {
+	if err = r.CheckEncode(); err != nil {
+		return
+	}
+	if opts.Markdown {
+		var b []byte
+		if b, err = r.limits.readAll(rd); err != nil {
+			return
+		}
+		rd = bytes.NewReader(blackfriday.Run(b))
+	} else if r.limits.MaxInputBytes > 0 {
+		rd = &limitReader{reader: rd, max: r.limits.MaxInputBytes, limit: "input"}
+	}
+	if rd, err = r.decodeReader(rd); err != nil {
+		return
+	}
+
+	e := &encoder{
+		c:      r.newConverter(ctx, opts.Domain),
+		w:      bufio.NewWriter(w),
+		stack:  []*streamFrame{{}},
+		quirks: true,
+	}
+	e.c.stream = true
+	err = e.encode(html.NewTokenizer(rd))
+	for len(e.stack) > 1 {
+		e.pop()
+	}
+	e.finishText(e.top())
+	// 与 Parse 一致，没有节点时为 null
+	if e.top().written > 0 {
+		e.w.WriteByte(']')
+	} else {
+		e.w.WriteString("null")
+	}
+	if flushErr := e.w.Flush(); err == nil {
+		err = flushErr
+	}
+	return
+}
+
+// limitReader 读取超出限制时返回 LimitError
+type limitReader struct {
+	reader    io.Reader
+	read, max int64
+	limit     string // LimitError 中的 Limit
+}
+
+func (l *limitReader) Read(p []byte) (n int, err error) {
+	n, err = l.reader.Read(p)
+	if l.read += int64(n); l.read > l.max {
+		return n, &LimitError{Limit: l.limit, Max: l.max}
+	}
+	return
+}
+
+// streamFrame 尚未结束的元素
+type streamFrame struct {
+	tag         string // 原始标签名称，根节点为空
+	ns          string // svg、math 等外部内容的命名空间，HTML 元素为空
+	integration bool   // 外部内容中的 HTML 集成点，如 svg 中的 foreignObject
+	marker      bool   // 是否在活动格式化元素列表中插入了标记，如 td、th
+	closed      bool   // 是否已经结束
+	depth       int
+	written     int        // 已输出的子节点数量
+	hasElement  bool       // 是否有子元素
+	pending     string     // 尚未处理的文本
+	texts       []string   // 没有子元素时暂存的文本，结束时合并为一个文本节点
+	skip        bool       // 忽略子节点
+	node        *Node      // 需要在内存中构造子树时，正在构造的节点
+	src         *html.Node // 与 node 对应的原始元素，供转换函数使用
+	trimLF      bool       // 与 HTML 解析一致，忽略 pre 等元素开头的第一个换行符
+	raw         bool       // 只构造原始的子树，结束时再转换，用于需要高亮的 pre 以及 picture
+	picture     bool       // 结束时与 Parse 一致地将 <picture> 合并为 img
+}
+
+// formatEntry 活动格式化元素列表中的元素，frame 为 nil 时为标记
+type formatEntry struct {
+	token html.Token
+	frame *streamFrame
+}
+
+type encoder struct {
+	c          *converter
+	w          *bufio.Writer
+	stack      []*streamFrame
+	formatting []*formatEntry // 活动格式化元素列表，用于恢复被提前结束的 b、i 等元素
+	inBody     bool
+	quirks     bool // 没有 <!DOCTYPE html> 时为怪异模式，此时 table 不会结束 p
+}
+
+var (
+	// 只能出现在 head 中的元素，body 之前出现时忽略
+	headTags = map[string]bool{
+		"base": true, "link": true, "meta": true, "noscript": true, "script": true, "style": true, "template": true, "title": true,
+	}
+	// 开始时结束 p 的块级元素
+	closeByBlock = map[string]bool{
+		"address": true, "article": true, "aside": true, "blockquote": true, "center": true, "details": true, "dialog": true,
+		"dir": true, "div": true, "dl": true, "fieldset": true, "figcaption": true, "figure": true, "footer": true, "form": true,
+		"h1": true, "h2": true, "h3": true, "h4": true, "h5": true, "h6": true, "header": true, "hgroup": true, "hr": true,
+		"main": true, "menu": true, "nav": true, "ol": true, "p": true, "pre": true, "section": true, "summary": true,
+		"ul": true, "listing": true, "plaintext": true, "search": true,
+	}
+	// 作用域的边界，查找需要结束的元素时不会越过这些元素
+	scopeTags = map[string]bool{
+		"applet": true, "caption": true, "marquee": true, "object": true, "table": true, "td": true, "template": true, "th": true,
+	}
+	// 特殊元素，结束 span 等普通元素时不会越过这些元素
+	specialTags = map[string]bool{
+		"address": true, "applet": true, "area": true, "article": true, "aside": true, "base": true, "basefont": true,
+		"bgsound": true, "blockquote": true, "body": true, "br": true, "button": true, "caption": true, "center": true,
+		"col": true, "colgroup": true, "dd": true, "details": true, "dir": true, "div": true, "dl": true, "dt": true,
+		"embed": true, "fieldset": true, "figcaption": true, "figure": true, "footer": true, "form": true, "frame": true,
+		"frameset": true, "h1": true, "h2": true, "h3": true, "h4": true, "h5": true, "h6": true, "head": true,
+		"header": true, "hgroup": true, "hr": true, "html": true, "iframe": true, "img": true, "input": true,
+		"keygen": true, "li": true, "link": true, "listing": true, "main": true, "marquee": true, "menu": true,
+		"meta": true, "nav": true, "noembed": true, "noframes": true, "noscript": true, "object": true, "ol": true,
+		"p": true, "param": true, "plaintext": true, "pre": true, "script": true, "search": true, "section": true,
+		"select": true, "source": true, "style": true, "summary": true, "table": true, "tbody": true, "td": true,
+		"template": true, "textarea": true, "tfoot": true, "th": true, "thead": true, "title": true, "tr": true,
+		"track": true, "ul": true, "wbr": true, "xmp": true,
+	}
+	// 格式化元素，被块级元素等提前结束时会在之后的内容中恢复
+	formattingTags = map[string]bool{
+		"a": true, "b": true, "big": true, "code": true, "em": true, "font": true, "i": true, "nobr": true, "s": true,
+		"small": true, "strike": true, "strong": true, "tt": true, "u": true,
+	}
+	// 在活动格式化元素列表中插入标记的元素，其中的格式化元素不会在元素之外恢复
+	markerTags = map[string]bool{
+		"applet": true, "caption": true, "marquee": true, "object": true, "td": true, "template": true, "th": true,
+	}
+	tableTags = map[string]bool{
+		"caption": true, "col": true, "colgroup": true, "table": true, "tbody": true, "td": true, "tfoot": true,
+		"th": true, "thead": true, "tr": true,
+	}
+	headingTags = []string{"h1", "h2", "h3", "h4", "h5", "h6"}
+)
+
+func (e *encoder) encode(z *html.Tokenizer) error {
+	for {
+		tt := z.Next()
+		switch tt {
+		case html.ErrorToken:
+			if err := z.Err(); err != io.EOF {
+				return err
+			}
+			return e.c.err
+		case html.TextToken:
+			e.text(string(z.Text()))
+		case html.CommentToken:
+			e.top().trimLF = false
+			e.flushText(e.top())
+		case html.DoctypeToken:
+			if !e.inBody {
+				fields := strings.Fields(string(z.Text()))
+				e.quirks = len(fields) == 0 || !strings.EqualFold(fields[0], "html")
+			}
+		case html.StartTagToken, html.SelfClosingTagToken:
+			token := z.Token()
+			e.start(token, tt == html.SelfClosingTagToken)
+		case html.EndTagToken:
+			name, _ := z.TagName()
+			e.end(string(name))
+		}
+		if e.c.err != nil {
+			return e.c.err
+		}
+	}
+}
+
+func (e *encoder) top() *streamFrame {
+	return e.stack[len(e.stack)-1]
+}
+
+// text 暂存文本，与 HTML 解析一致，相邻的文本会被合并为一个文本节点
+func (e *encoder) text(text string) {
+	f := e.top()
+	if f.trimLF {
+		f.trimLF = false
+		text = strings.TrimPrefix(text, "\n")
+	}
+	if text == "" || f.skip {
+		return
+	}
+	if !e.inBody {
+		// body 之前的空白字符被忽略
+		if text = strings.TrimLeft(text, " \t\n\r\f"); text == "" {
+			return
+		}
+		e.inBody = true
+	}
+	// 表格中的文本不会恢复格式化元素
+	if (f.ns == "" || f.integration) && !tableTags[f.tag] {
+		e.reconstruct()
+		f = e.top()
+	}
+	if f.raw {
+		f.src.AppendChild(&html.Node{Type: html.TextNode, Data: text})
+		return
+	}
+	f.pending += text
+}
+
+// flushText 处理暂存的文本：有子元素时直接输出，否则留到元素结束时合并输出
+func (e *encoder) flushText(f *streamFrame) {
+	if f.pending == "" {
+		return
+	}
+	text := f.pending
+	f.pending = ""
+	if f.src != nil {
+		f.src.AppendChild(&html.Node{Type: html.TextNode, Data: text})
+	}
+	if f.hasElement {
+		e.emitText(f, text)
+	} else {
+		f.texts = append(f.texts, text)
+	}
+}
+
+// start 按照 HTML5 树构建的规则处理开始标签，如隐式结束 p、li，补全 tbody 以及恢复格式化元素
+func (e *encoder) start(token html.Token, selfClosing bool) {
+	name := token.Data
+	if !e.inBody {
+		switch {
+		case name == "html" || name == "head":
+			return
+		case name == "body":
+			e.inBody = true
+			return
+		case headTags[name]:
+			if name == "base" && e.c.baseHref == nil {
+				for _, attr := range token.Attr {
+					if attr.Key == "href" {
+						e.c.setBase(attr.Val)
+						break
+					}
+				}
+			}
+			if !voidTags[name] && !selfClosing {
+				e.stack = append(e.stack, &streamFrame{tag: name, skip: true})
+			}
+			return
+		}
+		e.inBody = true
+	} else if name == "html" || name == "head" || name == "body" {
+		return
+	}
+
+	if ns := e.namespace(name, token.Attr); ns != "" {
+		adjustForeignAttrs(ns, token.Attr)
+		e.insert(token, ns, selfClosing)
+		return
+	}
+	// 外部内容中出现 HTML 元素时结束外部内容
+	for f := e.top(); f.ns != "" && !f.integration; f = e.top() {
+		e.pop()
+	}
+	if name == "image" {
+		name, token.Data, token.DataAtom = "img", "img", atom.Img
+	}
+
+	switch {
+	case tableTags[name]:
+		if !e.table(name) {
+			return
+		}
+		for tag := impliedParent(e.top(), name); tag != ""; tag = impliedParent(e.top(), name) {
+			if e.insert(html.Token{Type: html.StartTagToken, Data: tag, DataAtom: atom.Lookup([]byte(tag))}, "", false) == nil {
+				return
+			}
+		}
+	case name == "li" || name == "dd" || name == "dt":
+		e.closeListItem(name)
+	case closeByBlock[name]:
+		e.closeP()
+		if f := e.top(); isHeading(name) && f.ns == "" && isHeading(f.tag) {
+			e.pop()
+		}
+	case headTags[name]:
+		// 与 head 中的元素一样直接插入
+	case name == "a":
+		// 嵌套的 a 结束之前的 a
+		if i := e.formattingIndex("a"); i >= 0 {
+			f := e.formatting[i].frame
+			e.adopt("a")
+			e.removeFormatting(f)
+		}
+		e.reconstruct()
+	case name == "button":
+		e.closeTo([]string{"button"})
+		e.reconstruct()
+	case name == "option" || name == "optgroup":
+		if f := e.top(); f.ns == "" && f.tag == "option" {
+			e.pop()
+		}
+		e.reconstruct()
+	default:
+		e.reconstruct()
+	}
+
+	f := e.insert(token, "", selfClosing)
+	if f == nil || f.closed {
+		return
+	}
+	if formattingTags[name] {
+		e.pushFormatting(token, f)
+	} else if markerTags[name] {
+		f.marker = true
+		e.formatting = append(e.formatting, &formatEntry{})
+	}
+}
+
+// namespace 返回新元素的命名空间，HTML 元素为空
+func (e *encoder) namespace(name string, attrs []html.Attribute) string {
+	if f := e.top(); f.ns != "" && !f.integration {
+		if breakout(name, attrs) {
+			return ""
+		}
+		return f.ns
+	}
+	if name == "svg" || name == "math" {
+		return name
+	}
+	return ""
+}
+
+// insert 插入元素并输出元素本身，返回元素对应的 frame，void 元素返回时已经结束，超出限制时返回 nil
+func (e *encoder) insert(token html.Token, ns string, selfClosing bool) *streamFrame {
+	name := token.Data
+	parent := e.top()
+	parent.trimLF = false
+	// HTML 元素忽略自闭合标记，svg 和 math 中的元素则需要遵循
+	void := voidTags[name] || (selfClosing && ns != "")
+	f := &streamFrame{tag: name, ns: ns, integration: integrationPoint(ns, name, token.Attr), depth: parent.depth + 1}
+	item := &html.Node{Type: html.ElementNode, Data: name, DataAtom: token.DataAtom, Namespace: ns, Attr: token.Attr}
+	switch {
+	case parent.raw:
+		parent.src.AppendChild(item)
+		f.raw, f.src = true, item
+	case parent.skip:
+		f.skip = true
+	default:
+		e.flushText(parent)
+		if !parent.hasElement {
+			parent.hasElement = true
+			for _, text := range parent.texts {
+				e.emitText(parent, text)
+			}
+			parent.texts = nil
+		}
+		if name == "picture" && ns == "" {
+			f.raw, f.src, f.picture = true, item, true
+			break
+		}
+
+		h, tag, ok := e.c.element(item)
+		f.skip, f.trimLF = !ok, ns == "" && (name == "pre" || name == "listing" || name == "textarea")
+		if ok && (!e.c.count(h, f.depth) || !e.c.countNodes(h.Children, f.depth+1)) {
+			return nil
+		}
+		if !ok {
+			break
+		}
+		// 需要高亮的 pre 在结束时根据完整的子树高亮
+		f.raw = tag == "pre" && e.c.r.highlight != nil && len(h.Children) == 0
+		if parent.node != nil || len(h.Children) > 0 || e.c.r.hasTransform(tag) || f.raw {
+			f.node, f.src = &h, item
+			f.skip = len(h.Children) > 0
+			if parent.src != nil {
+				parent.src.AppendChild(item)
+			}
+		} else {
+			e.separate(parent)
+			e.w.WriteString(`{"name":`)
+			e.writeJSON(h.Name)
+			if len(h.Attrs) > 0 {
+				e.w.WriteString(`,"attrs":`)
+				e.writeJSON(h.Attrs)
+			}
+		}
+	}
+
+	e.stack = append(e.stack, f)
+	if void {
+		e.pop()
+	}
+	return f
+}
+
+// end 按照 HTML5 树构建的规则处理结束标签，找不到对应的元素或者中间有作用域边界时忽略
+func (e *encoder) end(name string) {
+	if !e.inBody {
+		for i := len(e.stack) - 1; i > 0; i-- {
+			if e.stack[i].tag == name {
+				e.popTo(i)
+				return
+			}
+		}
+		if name != "br" {
+			return
+		}
+		e.inBody = true
+	}
+	switch name {
+	case "html", "head", "body":
+		return
+	}
+	// 外部内容中的元素
+	for i := len(e.stack) - 1; i > 0 && e.stack[i].ns != ""; i-- {
+		if e.stack[i].tag == name {
+			e.popTo(i)
+			return
+		}
+	}
+
+	switch {
+	case name == "p":
+		if i := e.find([]string{"p"}, "button"); i > 0 {
+			e.popTo(i)
+			return
+		}
+		// 没有对应的 p 时插入空的 p
+		if f := e.insert(html.Token{Type: html.StartTagToken, Data: "p", DataAtom: atom.P}, "", false); f != nil {
+			e.pop()
+		}
+	case name == "br":
+		e.start(html.Token{Type: html.StartTagToken, Data: "br", DataAtom: atom.Br}, false)
+	case name == "li":
+		e.closeTo([]string{"li"}, "ol", "ul")
+	case isHeading(name):
+		e.closeTo(headingTags)
+	case formattingTags[name]:
+		if !e.adopt(name) {
+			e.closeAny(name)
+		}
+	case tableTags[name]:
+		for i := len(e.stack) - 1; i > 0; i-- {
+			if f := e.stack[i]; f.ns == "" && f.tag == name {
+				e.popTo(i)
+				return
+			} else if f.ns == "" && (f.tag == "table" || f.tag == "template") {
+				return
+			}
+		}
+	case specialTags[name]:
+		e.closeTo([]string{name})
+	default:
+		e.closeAny(name)
+	}
+}
+
+// table 处理表格相关的开始标签：结束当前的单元格、行等元素，返回 false 时忽略该元素，如表格之外的 tr、td
+func (e *encoder) table(name string) bool {
+	for {
+		i := len(e.stack) - 1
+		for i > 0 && (e.stack[i].ns != "" || !tableTags[e.stack[i].tag]) {
+			i--
+		}
+		switch tag := e.stack[i].tag; {
+		case i == 0 || tag == "td" || tag == "th" || tag == "caption":
+			if name == "table" {
+				if !e.quirks {
+					e.closeP()
+				}
+				return true
+			}
+			if i == 0 {
+				return false
+			}
+		case tag == "tr":
+			if name == "td" || name == "th" {
+				e.popTo(i + 1)
+				return true
+			}
+		case tag == "tbody" || tag == "thead" || tag == "tfoot":
+			if name == "tr" || name == "td" || name == "th" {
+				e.popTo(i + 1)
+				return true
+			}
+		case tag == "colgroup":
+			if name == "col" {
+				e.popTo(i + 1)
+				return true
+			}
+		case tag == "table":
+			if name != "table" {
+				e.popTo(i + 1)
+				return true
+			}
+		}
+		e.popTo(i)
+	}
+}
+
+// impliedParent 返回表格中省略的父元素，如 table 中的 tr 需要补全 tbody
+func impliedParent(f *streamFrame, name string) string {
+	if f.ns != "" {
+		return ""
+	}
+	switch f.tag {
+	case "table":
+		switch name {
+		case "tr", "td", "th":
+			return "tbody"
+		case "col":
+			return "colgroup"
+		}
+	case "tbody", "thead", "tfoot":
+		if name == "td" || name == "th" {
+			return "tr"
+		}
+	}
+	return ""
+}
+
+// closeListItem 新的 li 结束之前的 li，dd 和 dt 同理
+func (e *encoder) closeListItem(name string) {
+	for i := len(e.stack) - 1; i > 0; i-- {
+		f := e.stack[i]
+		if f.ns == "" && (f.tag == name || name != "li" && (f.tag == "dd" || f.tag == "dt")) {
+			e.popTo(i)
+			break
+		}
+		if f.special() && !(f.ns == "" && (f.tag == "address" || f.tag == "div" || f.tag == "p")) {
+			break
+		}
+	}
+	e.closeP()
+}
+
+// closeP 结束 button 作用域中的 p
+func (e *encoder) closeP() {
+	e.closeTo([]string{"p"}, "button")
+}
+
+// closeTo 结束作用域中最近的 tags 之一及其中的元素，extra 为额外的作用域边界
+func (e *encoder) closeTo(tags []string, extra ...string) {
+	if i := e.find(tags, extra...); i > 0 {
+		e.popTo(i)
+	}
+}
+
+// find 从栈顶开始在作用域中查找 tags 之一，遇到 table、td 等作用域边界时停止，没有找到时返回 -1
+func (e *encoder) find(tags []string, extra ...string) int {
+	for i := len(e.stack) - 1; i > 0; i-- {
+		f := e.stack[i]
+		if f.ns == "" && hasTag(tags, f.tag) {
+			return i
+		}
+		if f.boundary() || f.ns == "" && hasTag(extra, f.tag) {
+			return -1
+		}
+	}
+	return -1
+}
+
+// closeAny 结束最近的同名元素，中间有 div 等特殊元素时忽略
+func (e *encoder) closeAny(name string) {
+	for i := len(e.stack) - 1; i > 0; i-- {
+		f := e.stack[i]
+		if f.ns == "" && f.tag == name {
+			e.popTo(i)
+			return
+		}
+		if f.special() {
+			return
+		}
+	}
+}
+
+// popTo 结束栈中第 i 个以及之后的元素
+func (e *encoder) popTo(i int) {
+	for len(e.stack) > i {
+		e.pop()
+	}
+}
+
+// boundary 判断元素是否为作用域的边界
+func (f *streamFrame) boundary() bool {
+	if f.ns != "" {
+		return f.integration || f.tag == "annotation-xml"
+	}
+	return scopeTags[f.tag]
+}
+
+func (f *streamFrame) special() bool {
+	if f.ns != "" {
+		return f.boundary()
+	}
+	return specialTags[f.tag]
+}
+
+// formattingIndex 在最后一个标记之后查找格式化元素，没有找到时返回 -1
+func (e *encoder) formattingIndex(name string) int {
+	for i := len(e.formatting) - 1; i >= 0 && e.formatting[i].frame != nil; i-- {
+		if e.formatting[i].token.Data == name {
+			return i
+		}
+	}
+	return -1
+}
+
+func (e *encoder) removeFormatting(f *streamFrame) {
+	for i, entry := range e.formatting {
+		if entry.frame == f {
+			e.formatting = append(e.formatting[:i], e.formatting[i+1:]...)
+			return
+		}
+	}
+}
+
+// pushFormatting 将格式化元素加入列表，与 HTML5 一致，最后一个标记之后最多保留 3 个标签和属性都相同的元素
+func (e *encoder) pushFormatting(token html.Token, f *streamFrame) {
+	same, first := 0, -1
+	for i := len(e.formatting) - 1; i >= 0 && e.formatting[i].frame != nil; i-- {
+		if sameElement(e.formatting[i].token, token) {
+			same, first = same+1, i
+		}
+	}
+	if same >= 3 {
+		e.formatting = append(e.formatting[:first], e.formatting[first+1:]...)
+	}
+	token.Attr = append([]html.Attribute(nil), token.Attr...)
+	e.formatting = append(e.formatting, &formatEntry{token: token, frame: f})
+}
+
+// adopt 结束格式化元素，返回 false 时按照普通元素处理。简化自 HTML5 的 adoption agency 算法：
+// 已经输出的元素无法移动，格式化元素中的块级元素会随之一同结束，而不是移动到格式化元素之后
+func (e *encoder) adopt(name string) bool {
+	i := e.formattingIndex(name)
+	if i < 0 {
+		return false
+	}
+	if f := e.formatting[i].frame; !f.closed {
+		for j := len(e.stack) - 1; e.stack[j] != f; j-- {
+			if e.stack[j].boundary() {
+				return true
+			}
+		}
+		for !f.closed {
+			e.pop()
+		}
+	}
+	e.formatting = append(e.formatting[:i], e.formatting[i+1:]...)
+	return true
+}
+
+// reconstruct 恢复被提前结束的格式化元素，如 <p><b>1</p>2 中的 2 仍然加粗
+func (e *encoder) reconstruct() {
+	i := len(e.formatting)
+	for i > 0 && e.formatting[i-1].frame != nil && e.formatting[i-1].frame.closed {
+		i--
+	}
+	for ; i < len(e.formatting); i++ {
+		entry := e.formatting[i]
+		token := entry.token
+		token.Attr = append([]html.Attribute(nil), token.Attr...)
+		if entry.frame = e.insert(token, "", false); entry.frame == nil {
+			e.formatting = e.formatting[:i]
+			return
+		}
+	}
+}
+
+func sameElement(a, b html.Token) bool {
+	if a.Data != b.Data || len(a.Attr) != len(b.Attr) {
+		return false
+	}
+	for _, x := range a.Attr {
+		found := false
+		for _, y := range b.Attr {
+			if x == y {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+func hasTag(tags []string, tag string) bool {
+	for _, t := range tags {
+		if t == tag {
+			return true
+		}
+	}
+	return false
+}
+
+func isHeading(tag string) bool {
+	return len(tag) == 2 && tag[0] == 'h' && tag[1] >= '1' && tag[1] <= '6'
+}
+
+// pop 结束栈顶的元素
+func (e *encoder) pop() {
+	f := e.top()
+	e.stack = e.stack[:len(e.stack)-1]
+	f.closed = true
+	if f.marker {
+		for len(e.formatting) > 0 {
+			entry := e.formatting[len(e.formatting)-1]
+			e.formatting = e.formatting[:len(e.formatting)-1]
+			if entry.frame == nil {
+				break
+			}
+		}
+	}
+	parent := e.top()
+
+	if f.picture {
+		e.picture(f, parent)
+		return
+	}
+	if f.skip && f.node == nil || f.raw && f.node == nil {
+		return
+	}
+
+	if f.raw {
+		e.highlight(f)
+	} else {
+		e.finishText(f)
+	}
+
+	if f.node == nil {
+		if f.written > 0 {
+			e.w.WriteByte(']')
+		}
+		e.w.WriteByte('}')
+		return
+	}
+
+	e.emit(parent, e.c.r.transform(*f.node, f.src, strings.ToLower(f.src.Data)))
+}
+
+// picture 与 Parse 一致地将 <picture> 合并为 img，再按照原有的规则转换
+func (e *encoder) picture(f, parent *streamFrame) {
+	wrap := &html.Node{Type: html.ElementNode, Data: "div", DataAtom: atom.Div}
+	wrap.AppendChild(f.src)
+	e.c.r.collapsePictures(wrap)
+	nodes := e.c.walk(wrap, f.depth)
+	if parent.src != nil {
+		for item := wrap.FirstChild; item != nil; item = wrap.FirstChild {
+			wrap.RemoveChild(item)
+			parent.src.AppendChild(item)
+		}
+	}
+	e.emit(parent, nodes)
+}
+
+// emit 输出 parent 的子节点，parent 在内存中构造时加入其子节点
+func (e *encoder) emit(parent *streamFrame, nodes []Node) {
+	if parent.node != nil {
+		parent.node.Children = append(parent.node.Children, nodes...)
+		return
+	}
+	for _, node := range nodes {
+		e.separate(parent)
+		e.writeJSON(node)
+	}
+}
+
+// highlight 高亮 pre 的子树，无法高亮时按照原有的规则转换
+func (e *encoder) highlight(f *streamFrame) {
+	c := e.c
+	if children, style := c.highlight(f.src); children != nil {
+		if c.countNodes(children, f.depth+1) {
+			f.node.Children = children
+		}
+		if style != "" {
+			f.node.Attrs["style"] += style
+		}
+		return
+	}
+	f.node.Children = c.walk(f.src, f.depth+1)
+}
+
+// finishText 元素结束时输出剩余的文本
+func (e *encoder) finishText(f *streamFrame) {
+	e.flushText(f)
+	if !f.hasElement && !f.skip {
+		if text := strings.Join(f.texts, ""); text != "" {
+			e.emitText(f, text)
+		}
+	}
+}
+
+// emitText 输出 f 的文本子节点
+func (e *encoder) emitText(f *streamFrame, text string) {
+	h := Node{Type: "text", Text: text}
+	if !e.c.count(h, f.depth+1) {
+		return
+	}
+	if f.node != nil {
+		f.node.Children = append(f.node.Children, h)
+		return
+	}
+	e.separate(f)
+	e.writeJSON(h)
+}
+
+// separate 在输出 f 的子节点之前写入分隔符
+func (e *encoder) separate(f *streamFrame) {
+	switch {
+	case f.written > 0:
+		e.w.WriteByte(',')
+	case f.depth > 0:
+		e.w.WriteString(`,"children":[`)
+	default:
+		e.w.WriteByte('[')
+	}
+	f.written++
+}
+
+func (e *encoder) writeJSON(v interface{}) {
+	b, _ := json.Marshal(v)
+	e.w.Write(b)
+}
+
+func (r *RichText) hasTransform(tag string) bool {
+	return len(r.transforms[tag]) > 0 || len(r.transforms[AnyTag]) > 0
+}
@@ -0,0 +1,103 @@
+package html2json
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"strings"
+	"testing"
+
+	"golang.org/x/net/html"
+	"golang.org/x/text/encoding/simplifiedchinese"
+)
+
+func TestRichText_Encode(t *testing.T) {
+	files := []string{"examples/media.html", "examples/uniapp.html", "examples/gin.html"}
+	htmls := []string{
+		`<p>hello <b>world</b><p>second<ul><li>one<li>two</ul><pre>
+code</pre><img src="a.png"><br/><video src="a.mp4"></video>`,
+		`<div>text<!-- comment -->text<script>alert(1)</script></div><table><tbody><tr><td>1<td>2</tbody></table><dl><dt>a<dd>b</dl>`,
+		// 不完整的 HTML 与 Parse 一样按照 HTML5 的规则处理
+		"",
+		"x<head></head>y",
+		"<table><tr><td>1",
+		"<table><td>a<td>b<tr><td>c</table>d",
+		"<tr><td>x",
+		"<p>a<div>b</div>c</p>",
+		"<p>a<table><tr><td>b</table>",
+		"<!DOCTYPE html><p>a<table><tr><td>b</table>",
+		"<b>bold<i>both</b>italic</i>",
+		"<p><b>x<i>y</p><p>z</p>",
+		"<table><tr><td><b>x</td><td>y</td></tr></table>",
+		"<b><b><b><b>x</b></b></b></b><p>y",
+		"<a href=x>a<a href=y>b</a>",
+		"<span><div>x</span>y</div>z",
+		"<ul><li>a<div><li>b</ul><h1>a<h2>b</h1>c",
+		`<svg viewBox="0 0 1 1"><clipPath></clipPath><a xlink:href="#x">t</a><p>x`,
+		"<svg><foreignObject><p>x</p></foreignObject></svg><math><mi>x<b>y</b></mi></math>",
+		"<pre><span>a</span>\nb</pre><pre><!-- c -->\nd</pre>",
+		`<p>x<picture><source srcset="a.webp" type="image/webp"><img src="a.png"></picture>y</p>`,
+	}
+	for _, file := range files {
+		b, _ := ioutil.ReadFile(file)
+		htmls = append(htmls, string(b))
+	}
+
+	r := NewDefault().RegisterTransform("b", func(node Node, src *html.Node) []Node {
+		node.Name = "strong"
+		return []Node{node}
+	})
+	for i, htmlStr := range htmls {
+		nodes, err := r.Parse(htmlStr, "https://www.bookstack.cn/static/")
+		if err != nil {
+			t.Fatal(err)
+		}
+		var buf bytes.Buffer
+		if err = r.Encode(&buf, strings.NewReader(htmlStr), EncodeOptions{Domain: "https://www.bookstack.cn/static/"}); err != nil {
+			t.Fatal(err)
+		}
+		if expect, got := toJSON(nodes), buf.String(); got != expect {
+			idx := 0
+			for idx < len(got) && idx < len(expect) && got[idx] == expect[idx] {
+				idx++
+			}
+			t.Errorf("%v: unexpected output at %v:\n%.200v\n%.200v", i, idx, got[idx:], expect[idx:])
+		}
+	}
+}
+
+func TestRichText_Encode_Error(t *testing.T) {
+	var buf bytes.Buffer
+	htmlStr := strings.Repeat("<div><p>hello</p>", 100)
+	err := NewDefault().SetLimits(Limits{MaxNodes: 50}).Encode(&buf, strings.NewReader(htmlStr), EncodeOptions{})
+	var e *LimitError
+	if !errors.As(err, &e) || e.Limit != "nodes" {
+		t.Errorf("expect nodes limit error, got %v", err)
+	}
+	var nodes []Node
+	if err = json.Unmarshal(buf.Bytes(), &nodes); err != nil {
+		t.Errorf("output should be valid json: %v", err)
+	}
+
+	buf.Reset()
+	err = NewDefault().SetSelectors("p").SetHeadingIDs(true).Encode(&buf, strings.NewReader(htmlStr), EncodeOptions{})
+	if !errors.Is(err, ErrEncodeOption) || !strings.Contains(err.Error(), "selectors, heading ids") || buf.Len() > 0 {
+		t.Errorf("expect option error without output, got %v %q", err, buf.String())
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err = rt.EncodeContext(ctx, &buf, strings.NewReader(htmlStr), EncodeOptions{}); !errors.Is(err, context.Canceled) {
+		t.Errorf("expect context canceled, got %v", err)
+	}
+}
+
+func TestRichText_Encode_charset(t *testing.T) {
+	gbk, _ := simplifiedchinese.GBK.NewEncoder().String(`<meta charset="gbk"><p>中文内容</p>`)
+	var buf bytes.Buffer
+	if err := NewDefault().Encode(&buf, strings.NewReader(gbk), EncodeOptions{}); err != nil || !strings.Contains(buf.String(), "中文内容") {
+		t.Errorf("unexpected output %v %v", buf.String(), err)
+	}
+}
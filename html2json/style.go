@@ -0,0 +1,345 @@
+package html2json
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/andybalholm/cascadia"
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// SetInlineStyles 设置是否将文档中 <style> 的样式规则按照选择器的优先级合并到匹配元素的 style 属性中，
+// 合并之后不再输出 <style> 元素。@media 等规则以及 :hover、::before 等无法内联的选择器会被忽略
+func (r *RichText) SetInlineStyles(inline bool) *RichText {
+	r.inlineStyles = inline
+	return r
+}
+
+var (
+	cssCommentRegexp = regexp.MustCompile(`(?s)/\*.*?\*/`)
+	importantRegexp  = regexp.MustCompile(`(?i)\s*!\s*important\s*$`)
+	// 与用户交互相关的伪类以及伪元素，无法内联到 style 属性中
+	dynamicRegexp = regexp.MustCompile(`(?i)::|:(hover|focus|active|visited|target|before|after|first-line|first-letter|selection|placeholder|focus-within|focus-visible)\b`)
+)
+
+// specificity 选择器的优先级：id 数量、class/属性/伪类数量、标签/伪元素数量
+type specificity [3]int
+
+func (s specificity) less(o specificity) bool {
+	for i := range s {
+		if s[i] != o[i] {
+			return s[i] < o[i]
+		}
+	}
+	return false
+}
+
+type cssDecl struct {
+	prop      string
+	value     string
+	important bool
+}
+
+type cssRule struct {
+	selector string
+	spec     specificity
+	decls    []cssDecl
+}
+
+// cssMatch 作用于元素的一条声明，用于按照层叠规则比较
+type cssMatch struct {
+	cssDecl
+	inline bool // 元素原有的 style 属性中的声明
+	spec   specificity
+	order  int
+}
+
+func (m cssMatch) less(o cssMatch) bool {
+	switch {
+	case m.important != o.important:
+		return o.important
+	case m.inline != o.inline:
+		return o.inline
+	case m.spec != o.spec:
+		return m.spec.less(o.spec)
+	}
+	return m.order < o.order
+}
+
+// inlineStyles 将文档中 <style> 的规则合并到匹配元素的 style 属性中，并移除 <style> 元素
+func inlineStyles(root *html.Node) {
+	var (
+		rules  []cssRule
+		styles []*html.Node
+	)
+	var find func(*html.Node)
+	find = func(n *html.Node) {
+		for child := n.FirstChild; child != nil; child = child.NextSibling {
+			if child.Type != html.ElementNode {
+				continue
+			}
+			if child.DataAtom == atom.Style {
+				styles = append(styles, child)
+				if media := strings.ToLower(getAttr(child, "media")); media == "" || strings.Contains(media, "all") || strings.Contains(media, "screen") {
+					rules = append(rules, parseStylesheet(nodeText(child))...)
+				}
+				continue
+			}
+			find(child)
+		}
+	}
+	find(root)
+	for _, node := range styles {
+		node.Parent.RemoveChild(node)
+	}
+
+	matches := make(map[*html.Node][]cssMatch)
+	var nodes []*html.Node
+	order := 0
+	for _, rule := range rules {
+		sel, err := cascadia.Compile(rule.selector)
+		if err != nil {
+			continue
+		}
+		for _, node := range sel.MatchAll(root) {
+			if _, ok := matches[node]; !ok {
+				nodes = append(nodes, node)
+			}
+			for i, decl := range rule.decls {
+				matches[node] = append(matches[node], cssMatch{cssDecl: decl, spec: rule.spec, order: order + i})
+			}
+		}
+		order += len(rule.decls)
+	}
+
+	for _, node := range nodes {
+		list := matches[node]
+		for i, decl := range parseDeclarations(getAttr(node, "style")) {
+			list = append(list, cssMatch{cssDecl: decl, inline: true, order: i})
+		}
+		winners := make(map[string]cssMatch)
+		for _, m := range list {
+			if w, ok := winners[m.prop]; !ok || w.less(m) {
+				winners[m.prop] = m
+			}
+		}
+		list = list[:0]
+		for _, m := range winners {
+			list = append(list, m)
+		}
+		// 按照层叠顺序输出，简写属性与具体属性同时存在时，优先级高的声明在后面
+		sort.Slice(list, func(i, j int) bool { return list[i].less(list[j]) })
+		var buf strings.Builder
+		for _, m := range list {
+			buf.WriteString(m.prop)
+			buf.WriteString(": ")
+			buf.WriteString(m.value)
+			if m.inline && m.important {
+				buf.WriteString(" !important")
+			}
+			buf.WriteByte(';')
+		}
+		setAttr(node, "style", buf.String())
+	}
+}
+
+// parseStylesheet 解析样式表，每个选择器对应一条规则，忽略 @ 规则
+func parseStylesheet(css string) (rules []cssRule) {
+	css = cssCommentRegexp.ReplaceAllString(css, "")
+	for i := 0; i < len(css); {
+		start := indexOutside(css[i:], "{;")
+		if start < 0 {
+			break
+		}
+		prelude := strings.TrimSpace(css[i : i+start])
+		if css[i+start] == ';' {
+			i += start + 1
+			continue
+		}
+		end := matchBrace(css, i+start)
+		block := css[i+start+1 : end]
+		if end < len(css) {
+			end++
+		}
+		i = end
+		if strings.HasPrefix(prelude, "@") {
+			continue
+		}
+		decls := parseDeclarations(block)
+		if len(decls) == 0 {
+			continue
+		}
+		for _, selector := range splitOutside(prelude, ',') {
+			if selector = strings.TrimSpace(selector); selector == "" || dynamicRegexp.MatchString(selector) {
+				continue
+			}
+			rules = append(rules, cssRule{selector: selector, spec: selectorSpecificity(selector), decls: decls})
+		}
+	}
+	return
+}
+
+// parseDeclarations 解析 style 属性或者样式规则中的声明
+func parseDeclarations(style string) (decls []cssDecl) {
+	for _, item := range splitOutside(style, ';') {
+		i := strings.IndexByte(item, ':')
+		if i < 0 {
+			continue
+		}
+		decl := cssDecl{prop: strings.ToLower(strings.TrimSpace(item[:i])), value: strings.TrimSpace(item[i+1:])}
+		if loc := importantRegexp.FindStringIndex(decl.value); loc != nil {
+			decl.value, decl.important = strings.TrimSpace(decl.value[:loc[0]]), true
+		}
+		if decl.prop != "" && decl.value != "" {
+			decls = append(decls, decl)
+		}
+	}
+	return
+}
+
+// selectorSpecificity 计算单个选择器的优先级
+func selectorSpecificity(selector string) (s specificity) {
+	for i := 0; i < len(selector); {
+		c := selector[i]
+		switch {
+		case c == '#':
+			s[0]++
+			i = skipIdent(selector, i+1)
+		case c == '.':
+			s[1]++
+			i = skipIdent(selector, i+1)
+		case c == '[':
+			s[1]++
+			if end := indexOutside(selector[i:], "]"); end >= 0 {
+				i += end + 1
+			} else {
+				i = len(selector)
+			}
+		case c == ':':
+			end := skipIdent(selector, i+1)
+			name := strings.ToLower(selector[i+1 : end])
+			i = end
+			var args string
+			if i < len(selector) && selector[i] == '(' {
+				j := matchParen(selector, i)
+				args = selector[i+1 : j]
+				i = j + 1
+			}
+			// :not() 的优先级为其参数的优先级
+			if name == "not" {
+				inner := selectorSpecificity(args)
+				for k := range s {
+					s[k] += inner[k]
+				}
+			} else {
+				s[1]++
+			}
+		case c == '*' || c == ' ' || c == '>' || c == '+' || c == '~' || c == '\t' || c == '\n':
+			i++
+		default:
+			s[2]++
+			i = skipIdent(selector, i+1)
+		}
+	}
+	return
+}
+
+func skipIdent(s string, i int) int {
+	for i < len(s) {
+		c := s[i]
+		if c == '\\' && i+1 < len(s) {
+			i += 2
+			continue
+		}
+		if !(c == '-' || c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c >= 0x80) {
+			break
+		}
+		i++
+	}
+	return i
+}
+
+// indexOutside 查找 chars 中任一字符第一次出现在引号和括号之外的位置
+func indexOutside(s, chars string) int {
+	var quote byte
+	depth := 0
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case c == '\\':
+			i++
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case depth == 0 && strings.IndexByte(chars, c) >= 0:
+			return i
+		case c == '(':
+			depth++
+		case c == ')' && depth > 0:
+			depth--
+		}
+	}
+	return -1
+}
+
+// splitOutside 在引号和括号之外按 sep 分割字符串
+func splitOutside(s string, sep byte) (items []string) {
+	for {
+		i := indexOutside(s, string(sep))
+		if i < 0 {
+			return append(items, s)
+		}
+		items = append(items, s[:i])
+		s = s[i+1:]
+	}
+}
+
+// matchBrace 返回与 s[open] 处的 { 匹配的 } 的位置，不存在时返回 len(s)
+func matchBrace(s string, open int) int {
+	depth := 0
+	for i := open; i < len(s); {
+		j := indexOutside(s[i:], "{}")
+		if j < 0 {
+			break
+		}
+		i += j
+		if s[i] == '{' {
+			depth++
+		} else if depth--; depth == 0 {
+			return i
+		}
+		i++
+	}
+	return len(s)
+}
+
+// matchParen 返回与 s[open] 处的 ( 匹配的 ) 的位置，不存在时返回 len(s)-1
+func matchParen(s string, open int) int {
+	depth := 0
+	for i := open; i < len(s); i++ {
+		switch s[i] {
+		case '(':
+			depth++
+		case ')':
+			if depth--; depth == 0 {
+				return i
+			}
+		}
+	}
+	return len(s) - 1
+}
+
+func setAttr(node *html.Node, key, val string) {
+	for i, a := range node.Attr {
+		if a.Namespace == "" && a.Key == key {
+			node.Attr[i].Val = val
+			return
+		}
+	}
+	node.Attr = append(node.Attr, html.Attribute{Key: key, Val: val})
+}
@@ -0,0 +1,54 @@
+package html2json
+
+import "testing"
+
+func TestRichText_SetInlineStyles(t *testing.T) {
+	htmlStr := `<html><head><style>
+/* 注释 */
+p { color: red; margin: 0 }
+.note { color: blue; font-size: 12px }
+#first { color: green }
+div > p.note:not(.hidden) { margin-top: 5px }
+p { font-weight: bold !important }
+a:hover { color: black }
+@media (max-width: 600px) { p { color: gray } }
+@import url("a.css");
+</style></head><body><div><p id="first" class="note">a</p><p class="note" style="color: purple;font-weight: normal">b</p><p>c<a href="#">d</a></p></div><style media="print">p { color: white }</style></body></html>`
+	nodes, err := rt.Clone().SetClassPrefix("").SetInlineStyles(true).Parse(htmlStr, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expect := `[{"name":"div","children":[` +
+		`{"name":"p","attrs":{"class":"note","id":"first","style":"margin: 0;font-size: 12px;margin-top: 5px;color: green;font-weight: bold;"},"children":[{"type":"text","text":"a"}]},` +
+		`{"name":"p","attrs":{"class":"note","style":"margin: 0;font-size: 12px;margin-top: 5px;color: purple;font-weight: bold;"},"children":[{"type":"text","text":"b"}]},` +
+		`{"name":"p","attrs":{"style":"color: red;margin: 0;font-weight: bold;"},"children":[{"type":"text","text":"c"},{"name":"a","attrs":{"href":"#"},"children":[{"type":"text","text":"d"}]}]}` +
+		`]}]`
+	if got := toJSON(nodes); got != expect {
+		t.Errorf("unexpected nodes:\n%v\n%v", got, expect)
+	}
+
+	// 默认不处理 <style>
+	if nodes, _ := rt.Clone().SetClassPrefix("").Parse(`<p>a</p><style>p{color:red}</style>`, ""); len(nodes) != 2 {
+		t.Errorf("unexpected nodes: %v", toJSON(nodes))
+	}
+}
+
+func TestSelectorSpecificity(t *testing.T) {
+	cases := map[string]specificity{
+		"*":                          {0, 0, 0},
+		"li":                         {0, 0, 1},
+		"ul li":                      {0, 0, 2},
+		"ul ol+li":                   {0, 0, 3},
+		"h1 + *[rel=up]":             {0, 1, 1},
+		"ul ol li.red":               {0, 1, 3},
+		"li.red.level":               {0, 2, 1},
+		"#x34y":                      {1, 0, 0},
+		"#s12:not(FOO)":              {1, 0, 1},
+		`a[title="a]b"]:first-child`: {0, 2, 1},
+	}
+	for selector, expect := range cases {
+		if got := selectorSpecificity(selector); got != expect {
+			t.Errorf("unexpected specificity of %v: %v, expect %v", selector, got, expect)
+		}
+	}
+}
@@ -25,8 +25,31 @@ var (
 		"a", "abbr", "address", "article", "aside", "b", "bdi", "bdo", "big", "blockquote", "br", "caption", "center", "cite", "code", "col", "colgroup", "dd", "del", "div", "dl", "dt", "em", "fieldset", "font", "footer", "h1", "h2", "h3", "h4", "h5", "h6", "header", "hr", "i", "img", "ins", "label", "legend", "li", "mark", "nav", "ol", "p", "pre", "q", "rt", "ruby", "s", "section", "small", "span", "strong", "sub", "sup", "table", "tbody", "td", "tfoot", "th", "thead", "tr", "tt", "u", "ul",
 	}
 	toutiaoTags = defaultTags // 没看到有限定的信任标签
+
+	// 小程序 rich-text 组件支持的属性，"*" 为全局属性。各小程序文档中对属性的限制相同，因此不区分小程序。
+	// audio、video、iframe 的属性在转为链接或者原生组件时使用
+	defaultAttrs = map[string][]string{
+		"*":        {"class", "style"},
+		"a":        {"href", "title"},
+		"img":      {"alt", "src", "height", "width"},
+		"table":    {"width"},
+		"td":       {"colspan", "height", "rowspan", "width"},
+		"th":       {"colspan", "height", "rowspan", "width"},
+		"col":      {"span", "width"},
+		"colgroup": {"span", "width"},
+		"ol":       {"start", "type"},
+		"audio":    {"src", "controls", "autoplay", "loop", "muted"},
+		"video":    {"src", "poster", "controls", "autoplay", "loop", "muted", "width", "height"},
+		"iframe":   {"src", "width", "height"},
+	}
 )
 
+// Profile 小程序 rich-text 组件的配置，包括信任的HTML标签以及各标签允许的属性
+type Profile struct {
+	Tags  []string            `json:"tags"`
+	Attrs map[string][]string `json:"attrs,omitempty"` // 标签 => 允许的属性，"*" 表示全部标签都允许的属性
+}
+
 type Tag string
 
 const (
@@ -56,3 +79,12 @@ func GetTags(cate Tag) []string {
 		return defaultTags
 	}
 }
+
+// GetProfile 返回小程序信任的HTML标签以及各标签允许的属性，cate 只影响标签，属性对全部小程序相同
+func GetProfile(cate Tag) Profile {
+	attrs := make(map[string][]string, len(defaultAttrs))
+	for tag, keys := range defaultAttrs {
+		attrs[tag] = append([]string{}, keys...)
+	}
+	return Profile{Tags: GetTags(cate), Attrs: attrs}
+}
@@ -0,0 +1,149 @@
+package html2json
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+	"unicode"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// TOCItem 目录项
+type TOCItem struct {
+	Text     string    `json:"text"`
+	Level    int       `json:"level"` // 1~6，对应 h1~h6
+	ID       string    `json:"id,omitempty"`
+	Index    int       `json:"index"` // 标题所在的顶层节点的下标
+	Path     Path      `json:"path"`  // 标题节点在节点树中的路径
+	Children []TOCItem `json:"children,omitempty"`
+}
+
+// SetHeadingIDs 设置是否为 h1~h6 生成 id，已有 id 的标题保持不变。
+// id 的生成规则与 markdown 的标题锚点一致：转为小写、去掉标点符号、空格替换为 -，中日韩文字保持不变，重复时添加 -1、-2 等后缀。
+// 文档内指向标题原有 id 或者标题锚点的 #fragment 链接会被替换为生成的 id
+func (r *RichText) SetHeadingIDs(enable bool) *RichText {
+	r.headingIDs = enable
+	return r
+}
+
+var headingElements = map[atom.Atom]bool{
+	atom.H1: true, atom.H2: true, atom.H3: true, atom.H4: true, atom.H5: true, atom.H6: true,
+}
+
+// headingLevel 返回标题节点的级别，不是标题时返回 0
+func headingLevel(name string) int {
+	if len(name) == 2 && name[0] == 'h' && name[1] >= '1' && name[1] <= '6' {
+		return int(name[1] - '0')
+	}
+	return 0
+}
+
+// slugify 将标题文本转换为锚点
+func slugify(text string) string {
+	var buf strings.Builder
+	for _, c := range strings.ToLower(strings.TrimSpace(text)) {
+		switch {
+		case unicode.IsLetter(c) || unicode.IsDigit(c) || unicode.Is(unicode.Mn, c) || c == '-' || c == '_':
+			buf.WriteRune(c)
+		case unicode.IsSpace(c):
+			buf.WriteByte('-')
+		}
+	}
+	return buf.String()
+}
+
+// assignHeadingIDs 为 body 中的标题生成 id，并替换指向标题的 #fragment 链接
+func assignHeadingIDs(root *html.Node) {
+	body := findElement(root, atom.Body)
+	if body == nil {
+		return
+	}
+
+	used := make(map[string]bool)
+	var headings, links []*html.Node
+	eachElement(body, func(node *html.Node) bool {
+		if id := getAttr(node, "id"); id != "" && !headingElements[node.DataAtom] {
+			used[id] = true
+		}
+		switch {
+		case headingElements[node.DataAtom]:
+			headings = append(headings, node)
+		case node.DataAtom == atom.A && strings.HasPrefix(strings.TrimSpace(getAttr(node, "href")), "#"):
+			links = append(links, node)
+		}
+		return true
+	})
+
+	// 原有的 id 以及标题锚点 => 生成的 id
+	targets := make(map[string]string)
+	for _, node := range headings {
+		origin := strings.TrimSpace(getAttr(node, "id"))
+		slug := slugify(nodeText(node))
+		id := origin
+		if id == "" || used[id] {
+			if id = slug; id == "" {
+				id = "heading"
+			}
+			for i, base := 1, id; used[id]; i++ {
+				id = fmt.Sprintf("%v-%v", base, i)
+			}
+		}
+		used[id] = true
+		setAttr(node, "id", id)
+		for _, key := range []string{origin, slug, id} {
+			if _, ok := targets[key]; key != "" && !ok {
+				targets[key] = id
+			}
+		}
+	}
+
+	for _, node := range links {
+		frag := strings.TrimPrefix(strings.TrimSpace(getAttr(node, "href")), "#")
+		if unescaped, err := url.PathUnescape(frag); err == nil {
+			frag = unescaped
+		}
+		id, ok := targets[frag]
+		if !ok {
+			id, ok = targets[slugify(frag)]
+		}
+		if ok {
+			setAttr(node, "href", "#"+id)
+		}
+	}
+}
+
+// buildTOC 根据标题节点生成嵌套的目录
+func buildTOC(nodes []Node) (toc []TOCItem) {
+	var items []TOCItem
+	Inspect(nodes, func(node *Node, path Path) WalkAction {
+		if level := headingLevel(node.Name); level > 0 {
+			items = append(items, TOCItem{
+				Text:  collapseSpace(InnerText(node.Children)),
+				Level: level,
+				ID:    node.Attrs["id"],
+				Index: path[0],
+				Path:  append(Path{}, path...),
+			})
+			return WalkSkip
+		}
+		return WalkContinue
+	})
+	return nestTOC(items)
+}
+
+// nestTOC 将级别更低的目录项作为前一个级别更高的目录项的子项
+func nestTOC(items []TOCItem) (toc []TOCItem) {
+	for i := 0; i < len(items); {
+		item := items[i]
+		j := i + 1
+		for j < len(items) && items[j].Level > item.Level {
+			j++
+		}
+		item.Children = nestTOC(items[i+1 : j])
+		toc = append(toc, item)
+		i = j
+	}
+	return
+}
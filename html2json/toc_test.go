@@ -0,0 +1,70 @@
+package html2json
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSlugify(t *testing.T) {
+	cases := map[string]string{
+		"Hello World!":            "hello-world",
+		" 安装 与 使用 ":               "安装-与-使用",
+		"1.2 API（接口）":             "12-api接口",
+		"snake_case & kebab-case": "snake_case--kebab-case",
+		"日本語のテキスト":                "日本語のテキスト",
+		"？！":                      "",
+	}
+	for text, expect := range cases {
+		if got := slugify(text); got != expect {
+			t.Errorf("unexpected slug of %q: %q, expect %q", text, got, expect)
+		}
+	}
+}
+
+func TestRichText_SetHeadingIDs(t *testing.T) {
+	htmlStr := `<p><a href="#安装">安装</a><a href="#%E4%BD%BF%E7%94%A8">使用</a><a href="#old">old</a><a href="#none">none</a></p>` +
+		`<h1>html2json</h1><div><h2>安装</h2></div><h3 id="old">Go Get</h3><h2>使用</h2><h2>使用</h2><h4>？</h4><p id="使用-2">p</p>`
+	r := rt.Clone().SetClassPrefix("").SetHeadingIDs(true)
+	res, err := r.ParseResult(htmlStr, "https://www.bookstack.cn/static/")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var ids, hrefs []string
+	Inspect(res.Nodes, func(node *Node, path Path) WalkAction {
+		if headingLevel(node.Name) > 0 {
+			ids = append(ids, node.Attrs["id"])
+		}
+		if node.Name == "a" {
+			hrefs = append(hrefs, node.Attrs["href"])
+		}
+		return WalkContinue
+	})
+	if expect := []string{"html2json", "安装", "old", "使用", "使用-1", "heading"}; !reflect.DeepEqual(ids, expect) {
+		t.Errorf("unexpected ids: %v", ids)
+	}
+	if expect := []string{"#安装", "#使用", "#old", "#none"}; !reflect.DeepEqual(hrefs, expect) {
+		t.Errorf("unexpected hrefs: %v", hrefs)
+	}
+
+	toc := []TOCItem{
+		{Text: "html2json", Level: 1, ID: "html2json", Index: 1, Path: Path{1}, Children: []TOCItem{
+			{Text: "安装", Level: 2, ID: "安装", Index: 2, Path: Path{2, 0}, Children: []TOCItem{
+				{Text: "Go Get", Level: 3, ID: "old", Index: 3, Path: Path{3}},
+			}},
+			{Text: "使用", Level: 2, ID: "使用", Index: 4, Path: Path{4}},
+			{Text: "使用", Level: 2, ID: "使用-1", Index: 5, Path: Path{5}, Children: []TOCItem{
+				{Text: "？", Level: 4, ID: "heading", Index: 6, Path: Path{6}},
+			}},
+		}},
+	}
+	if !reflect.DeepEqual(res.TOC, toc) {
+		t.Errorf("unexpected toc: %v", toJSON(res.TOC))
+	}
+
+	// 未开启时不生成 id，但依然返回目录
+	res, _ = rt.ParseResult(`<h2>a</h2><h1>b</h1>`, "")
+	if toc := []TOCItem{{Text: "a", Level: 2, Path: Path{0}}, {Text: "b", Level: 1, Index: 1, Path: Path{1}}}; !reflect.DeepEqual(res.TOC, toc) {
+		t.Errorf("unexpected toc: %v", toJSON(res.TOC))
+	}
+}
@@ -0,0 +1,53 @@
+package html2json
+
+import (
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// AnyTag 注册对全部元素生效的转换函数时使用的标签名称
+const AnyTag = "*"
+
+// Transform 元素转换函数。node 为经过内置规则处理之后的节点，其子节点已经转换完成；
+// src 为原始的 HTML 元素，可通过 src.Data 获取原始的标签名称。
+// 返回的节点将替换 node：返回 nil 表示删除该元素，返回 node.Children 表示去掉外层标签，
+// 也可以修改 node 的名称、属性和子节点后返回，或者返回自定义的节点
+type Transform func(node Node, src *html.Node) []Node
+
+// RegisterTransform 注册标签的转换函数，tag 为原始的标签名称，AnyTag 表示全部元素。
+// 同一元素的转换函数按照注册顺序执行，标签的转换函数先于 AnyTag 的转换函数执行，
+// 前一个函数返回的每个元素节点都会交给下一个函数处理
+func (r *RichText) RegisterTransform(tag string, fn Transform) *RichText {
+	if fn == nil {
+		return r
+	}
+	if r.transforms == nil {
+		r.transforms = make(map[string][]Transform)
+	}
+	tag = strings.ToLower(tag)
+	r.transforms[tag] = append(r.transforms[tag], fn)
+	return r
+}
+
+// transform 执行元素的转换函数
+func (r *RichText) transform(node Node, src *html.Node, tag string) []Node {
+	nodes := []Node{node}
+	if len(r.transforms) == 0 {
+		return nodes
+	}
+	for _, fns := range [][]Transform{r.transforms[tag], r.transforms[AnyTag]} {
+		for _, fn := range fns {
+			var next []Node
+			for _, n := range nodes {
+				if n.Type == "text" {
+					next = append(next, n)
+					continue
+				}
+				next = append(next, fn(n, src)...)
+			}
+			nodes = next
+		}
+	}
+	return nodes
+}
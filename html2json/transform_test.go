@@ -0,0 +1,40 @@
+package html2json
+
+import (
+	"testing"
+
+	"golang.org/x/net/html"
+)
+
+func TestRichText_RegisterTransform(t *testing.T) {
+	r := NewDefault().SetClassPrefix("").
+		// 删除广告
+		RegisterTransform("ins", func(node Node, src *html.Node) []Node {
+			return nil
+		}).
+		// 去掉 font 标签
+		RegisterTransform("font", func(node Node, src *html.Node) []Node {
+			return node.Children
+		}).
+		// 将 BookStack 的提示块替换为 blockquote
+		RegisterTransform("div", func(node Node, src *html.Node) []Node {
+			if node.Attrs["class"] != "callout" {
+				return []Node{node}
+			}
+			return []Node{{Name: "blockquote", Children: append([]Node{{Type: "text", Text: "Tips: "}}, node.Children...)}}
+		}).
+		// 全部元素都去掉 id
+		RegisterTransform(AnyTag, func(node Node, src *html.Node) []Node {
+			delete(node.Attrs, "id")
+			return []Node{node}
+		})
+
+	nodes, err := r.Parse(`<div id="a"><ins>ads</ins><font color="red">red</font><div class="callout">hello</div></div>`, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expect := `[{"name":"div","children":[{"type":"text","text":"red"},{"name":"blockquote","children":[{"type":"text","text":"Tips: "},{"type":"text","text":"hello"}]}]}]`
+	if got := toJSON(nodes); got != expect {
+		t.Errorf("unexpected nodes:\n%v\n%v", got, expect)
+	}
+}
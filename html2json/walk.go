@@ -0,0 +1,95 @@
+package html2json
+
+import (
+	"strconv"
+	"strings"
+)
+
+// WalkAction 控制 Walk 的遍历行为
+type WalkAction int
+
+const (
+	WalkContinue WalkAction = iota // 继续遍历
+	WalkSkip                       // 跳过当前节点的子节点，只在 Enter 中有效
+	WalkStop                       // 终止遍历
+)
+
+// Path 为节点在树中的位置，每一级为该节点在其父节点 Children 中的下标
+type Path []int
+
+func (p Path) String() string {
+	items := make([]string, len(p))
+	for i, idx := range p {
+		items[i] = strconv.Itoa(idx)
+	}
+	return strings.Join(items, ".")
+}
+
+// Visitor 节点访问者。Enter 在访问子节点之前调用，Leave 在访问子节点之后调用。
+// path 在遍历过程中会被复用，需要保留时请自行拷贝
+type Visitor interface {
+	Enter(node *Node, path Path) WalkAction
+	Leave(node *Node, path Path) WalkAction
+}
+
+// WalkFunc 只关心 Enter 的访问函数
+type WalkFunc func(node *Node, path Path) WalkAction
+
+func (f WalkFunc) Enter(node *Node, path Path) WalkAction { return f(node, path) }
+
+func (f WalkFunc) Leave(node *Node, path Path) WalkAction { return WalkContinue }
+
+// Walk 深度优先遍历节点树，node 为指向树中节点的指针，可直接修改。
+// 返回 false 表示遍历被 WalkStop 终止
+func Walk(nodes []Node, v Visitor) bool {
+	return walk(nodes, v, make(Path, 0, 16))
+}
+
+// Inspect 以 WalkFunc 遍历节点树
+func Inspect(nodes []Node, fn WalkFunc) bool {
+	return Walk(nodes, fn)
+}
+
+func walk(nodes []Node, v Visitor, path Path) bool {
+	for i := range nodes {
+		p := append(path, i)
+		switch v.Enter(&nodes[i], p) {
+		case WalkStop:
+			return false
+		case WalkSkip:
+		default:
+			if !walk(nodes[i].Children, v, p) {
+				return false
+			}
+		}
+		if v.Leave(&nodes[i], p) == WalkStop {
+			return false
+		}
+	}
+	return true
+}
+
+// Get 根据 path 获取节点，path 无效时返回 nil
+func Get(nodes []Node, path Path) *Node {
+	var node *Node
+	for _, idx := range path {
+		if idx < 0 || idx >= len(nodes) {
+			return nil
+		}
+		node = &nodes[idx]
+		nodes = node.Children
+	}
+	return node
+}
+
+// InnerText 返回节点树中全部文本节点的内容
+func InnerText(nodes []Node) string {
+	var buf strings.Builder
+	Inspect(nodes, func(node *Node, path Path) WalkAction {
+		if node.Type == "text" {
+			buf.WriteString(node.Text)
+		}
+		return WalkContinue
+	})
+	return buf.String()
+}
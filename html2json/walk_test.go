@@ -0,0 +1,61 @@
+package html2json
+
+import (
+	"strings"
+	"testing"
+)
+
+type recorder struct {
+	events []string
+	skip   string
+	stop   string
+}
+
+func (r *recorder) Enter(node *Node, path Path) WalkAction {
+	r.events = append(r.events, "enter:"+node.Name+node.Text+"@"+path.String())
+	switch {
+	case node.Name != "" && node.Name == r.stop:
+		return WalkStop
+	case node.Name != "" && node.Name == r.skip:
+		return WalkSkip
+	}
+	return WalkContinue
+}
+
+func (r *recorder) Leave(node *Node, path Path) WalkAction {
+	r.events = append(r.events, "leave:"+node.Name+node.Text+"@"+path.String())
+	return WalkContinue
+}
+
+func TestWalk(t *testing.T) {
+	nodes, err := rt.Parse(`<div><p>hello</p><span>world</span></div><hr>`, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rec := &recorder{}
+	if !Walk(nodes, rec) {
+		t.Fatal("walk should not be stopped")
+	}
+	expect := "enter:div@0 enter:p@0.0 enter:hello@0.0.0 leave:hello@0.0.0 leave:p@0.0 " +
+		"enter:span@0.1 enter:world@0.1.0 leave:world@0.1.0 leave:span@0.1 leave:div@0 enter:hr@1 leave:hr@1"
+	if got := strings.Join(rec.events, " "); got != expect {
+		t.Errorf("unexpected events:\n%v\n%v", got, expect)
+	}
+
+	rec = &recorder{skip: "p", stop: "span"}
+	if Walk(nodes, rec) {
+		t.Fatal("walk should be stopped")
+	}
+	expect = "enter:div@0 enter:p@0.0 leave:p@0.0 enter:span@0.1"
+	if got := strings.Join(rec.events, " "); got != expect {
+		t.Errorf("unexpected events:\n%v\n%v", got, expect)
+	}
+
+	if node := Get(nodes, Path{0, 1, 0}); node == nil || node.Text != "world" {
+		t.Errorf("unexpected node: %v", toJSON(node))
+	}
+	if InnerText(nodes) != "helloworld" {
+		t.Errorf("unexpected text: %v", InnerText(nodes))
+	}
+}
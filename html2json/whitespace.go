@@ -0,0 +1,119 @@
+package html2json
+
+import (
+	"regexp"
+	"strings"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// Whitespace 文本中空白字符的处理方式
+type Whitespace int
+
+const (
+	// WhitespacePreserve 保留原始的空白字符，默认值
+	WhitespacePreserve Whitespace = iota
+	// WhitespaceNormal 按照 CSS white-space: normal 的规则合并连续的空白、去掉块级元素首尾的空白以及块级元素之间的空白文本，
+	// pre、code、textarea 以及样式为 white-space: pre* 的元素中的空白保持不变
+	WhitespaceNormal
+)
+
+// SetWhitespace 设置文本中空白字符的处理方式
+func (r *RichText) SetWhitespace(mode Whitespace) *RichText {
+	r.whitespace = mode
+	return r
+}
+
+var (
+	spaceRegexp      = regexp.MustCompile(`[ \t\n\r\f]+`)
+	preserveRegexp   = regexp.MustCompile(`(?i)white-space\s*:\s*(pre|break-spaces)`)
+	preserveElements = map[atom.Atom]bool{
+		atom.Pre: true, atom.Code: true, atom.Textarea: true, atom.Listing: true, atom.Plaintext: true, atom.Xmp: true,
+	}
+	// 不显示的元素，其中的文本不参与空白处理
+	hiddenElements = map[atom.Atom]bool{
+		atom.Head: true, atom.Script: true, atom.Style: true, atom.Template: true, atom.Title: true, atom.Noscript: true,
+	}
+	blockLevel = map[atom.Atom]bool{
+		atom.Address: true, atom.Article: true, atom.Aside: true, atom.Blockquote: true, atom.Body: true, atom.Caption: true,
+		atom.Center: true, atom.Dd: true, atom.Details: true, atom.Dialog: true, atom.Dir: true, atom.Div: true, atom.Dl: true,
+		atom.Dt: true, atom.Fieldset: true, atom.Figcaption: true, atom.Figure: true, atom.Footer: true, atom.Form: true,
+		atom.H1: true, atom.H2: true, atom.H3: true, atom.H4: true, atom.H5: true, atom.H6: true, atom.Header: true,
+		atom.Hgroup: true, atom.Hr: true, atom.Html: true, atom.Legend: true, atom.Li: true, atom.Main: true, atom.Menu: true,
+		atom.Nav: true, atom.Ol: true, atom.Optgroup: true, atom.Option: true, atom.P: true, atom.Pre: true, atom.Section: true,
+		atom.Summary: true, atom.Table: true, atom.Tbody: true, atom.Td: true, atom.Tfoot: true, atom.Th: true, atom.Thead: true,
+		atom.Tr: true, atom.Ul: true, atom.Listing: true, atom.Plaintext: true, atom.Xmp: true,
+		// br 不是块级元素，但同样会开始新的一行
+		atom.Br: true,
+	}
+)
+
+// normalizeSpace 按照 white-space: normal 的规则处理 parent 中的文本节点，并移除处理后为空的文本节点
+func normalizeSpace(parent *html.Node) {
+	s := &spaceNormalizer{lastSpace: true}
+	s.walk(parent)
+	s.boundary()
+	for _, node := range s.empty {
+		node.Parent.RemoveChild(node)
+	}
+}
+
+type spaceNormalizer struct {
+	lastSpace bool       // 位于行首或者上一段文本以空白结尾，此时需要去掉文本开头的空白
+	lastText  *html.Node // 当前行最后一个文本节点，行结束时去掉末尾的空白
+	empty     []*html.Node
+}
+
+func (s *spaceNormalizer) walk(parent *html.Node) {
+	for node := parent.FirstChild; node != nil; node = node.NextSibling {
+		switch node.Type {
+		case html.TextNode:
+			s.text(node)
+		case html.ElementNode:
+			if hiddenElements[node.DataAtom] {
+				continue
+			}
+			block := blockLevel[node.DataAtom]
+			if block {
+				s.boundary()
+			}
+			if preserveElements[node.DataAtom] || preserveRegexp.MatchString(getAttr(node, "style")) {
+				s.lastSpace, s.lastText = false, nil
+			} else if node.FirstChild != nil {
+				s.walk(node)
+			} else if !block {
+				// img 等行内元素
+				s.lastSpace, s.lastText = false, nil
+			}
+			if block {
+				s.boundary()
+			}
+		}
+	}
+}
+
+func (s *spaceNormalizer) text(node *html.Node) {
+	text := spaceRegexp.ReplaceAllString(node.Data, " ")
+	if s.lastSpace {
+		text = strings.TrimPrefix(text, " ")
+	}
+	node.Data = text
+	if text == "" {
+		s.empty = append(s.empty, node)
+		return
+	}
+	s.lastSpace = strings.HasSuffix(text, " ")
+	s.lastText = node
+}
+
+// boundary 在块级元素的边界结束当前行
+func (s *spaceNormalizer) boundary() {
+	if s.lastText != nil {
+		s.lastText.Data = strings.TrimSuffix(s.lastText.Data, " ")
+		if s.lastText.Data == "" {
+			s.empty = append(s.empty, s.lastText)
+		}
+	}
+	s.lastSpace, s.lastText = true, nil
+}
@@ -0,0 +1,67 @@
+package html2json
+
+import (
+	"io/ioutil"
+	"testing"
+)
+
+func TestRichText_SetWhitespace(t *testing.T) {
+	cases := []struct {
+		html   string
+		expect string
+	}{
+		{"<div>\n\t<p>\n\thello   world!\n\t</p>\n\t<p>foo</p>\n</div>",
+			`[{"name":"div","children":[{"name":"p","children":[{"type":"text","text":"hello world!"}]},{"name":"p","children":[{"type":"text","text":"foo"}]}]}]`},
+		{"<p> a <b> b </b> <i>c</i> </p>",
+			`[{"name":"p","children":[{"type":"text","text":"a "},{"name":"b","children":[{"type":"text","text":"b "}]},{"name":"i","children":[{"type":"text","text":"c"}]}]}]`},
+		{"<p>line <br>\n next<img src=\"a.png\"> </p>",
+			`[{"name":"p","children":[{"type":"text","text":"line"},{"name":"br"},{"type":"text","text":"next"},{"name":"img","attrs":{"src":"a.png"}}]}]`},
+		{"<p>use <code>a  =  b</code> here</p>\n<pre>\n  x  \n\ty\n</pre>",
+			`[{"name":"p","children":[{"type":"text","text":"use "},{"name":"code","children":[{"type":"text","text":"a  =  b"}]},{"type":"text","text":" here"}]},{"name":"div","attrs":{"style":"` + preStyle + `"},"children":[{"type":"text","text":"  x  \n\ty\n"}]}]`},
+		{"<p>\u00a0 a</p><span style=\"white-space: pre-wrap\"> b  </span>",
+			"[{\"name\":\"p\",\"children\":[{\"type\":\"text\",\"text\":\"\u00a0 a\"}]}," +
+				`{"name":"span","attrs":{"style":"white-space: pre-wrap"},"children":[{"type":"text","text":" b  "}]}]`},
+	}
+	for _, engine := range []Engine{EngineGoquery, EngineNative} {
+		r := rt.Clone().SetClassPrefix("").SetEngine(engine).SetWhitespace(WhitespaceNormal)
+		for _, c := range cases {
+			nodes, err := r.Parse(c.html, "")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := toJSON(nodes); got != c.expect {
+				t.Errorf("unexpected nodes for %q:\n%v\n%v", c.html, got, c.expect)
+			}
+		}
+	}
+
+	// 默认保留原始的空白
+	if nodes, _ := rt.Clone().SetClassPrefix("").Parse("<p> a </p>\n", ""); toJSON(nodes) != `[{"name":"p","children":[{"type":"text","text":" a "}]},{"type":"text","text":"\n"}]` {
+		t.Errorf("unexpected nodes: %v", toJSON(nodes))
+	}
+}
+
+func TestRichText_SetWhitespace_examples(t *testing.T) {
+	b, err := ioutil.ReadFile("examples/uniapp.html")
+	if err != nil {
+		t.Fatal(err)
+	}
+	count := func(nodes []Node) (n int) {
+		Inspect(nodes, func(node *Node, path Path) WalkAction {
+			n++
+			return WalkContinue
+		})
+		return
+	}
+	origin, _ := rt.ParseByByte(b, "")
+	normal, _ := rt.Clone().SetWhitespace(WhitespaceNormal).ParseByByte(b, "")
+	if count(normal) >= count(origin) {
+		t.Errorf("expect fewer nodes, got %v and %v", count(normal), count(origin))
+	}
+	Inspect(normal, func(node *Node, path Path) WalkAction {
+		if node.Type == "text" && node.Text == "" {
+			t.Errorf("unexpected empty text at %v", path)
+		}
+		return WalkContinue
+	})
+}